@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"sort"
+	"sync"
+)
+
+// DataSourceOptions are the connection settings of a data source. Drivers
+// ignore the fields that do not apply to them.
+type DataSourceOptions struct {
+	Uri      string
+	Host     string
+	Port     string
+	Hosts    []string
+	Username string
+	Password string
+	Database string
+}
+
+// DataSourceDriver is registered by each backend under its data source type
+// (see generic.DataSourceType*). Backends register themselves on import, so
+// the package of the backend must be imported for its type to be available.
+type DataSourceDriver struct {
+	// Factory opens a connection, e.g. *sqlx.DB or *mongo.Client.
+	Factory func(opts *DataSourceOptions) (conn interface{}, err error)
+
+	// ConnectionString builds the connection string from the options.
+	ConnectionString func(opts *DataSourceOptions) (connStr string, err error)
+
+	// HealthCheck returns an error if a connection opened by Factory is unusable.
+	HealthCheck func(conn interface{}) (err error)
+}
+
+var _dataSourceDrivers = map[string]DataSourceDriver{}
+var _dataSourceDriversMu sync.RWMutex
+
+// RegisterDataSourceDriver makes a data source driver available under the
+// data source type. It panics if the driver is incomplete or if the type is
+// registered twice.
+func RegisterDataSourceDriver(dataSourceType string, driver DataSourceDriver) {
+	_dataSourceDriversMu.Lock()
+	defer _dataSourceDriversMu.Unlock()
+	if driver.Factory == nil || driver.ConnectionString == nil || driver.HealthCheck == nil {
+		panic("db: incomplete data source driver " + dataSourceType)
+	}
+	if _, ok := _dataSourceDrivers[dataSourceType]; ok {
+		panic("db: data source driver registered twice " + dataSourceType)
+	}
+	_dataSourceDrivers[dataSourceType] = driver
+}
+
+func GetDataSourceDriver(dataSourceType string) (driver DataSourceDriver, err error) {
+	_dataSourceDriversMu.RLock()
+	defer _dataSourceDriversMu.RUnlock()
+	driver, ok := _dataSourceDrivers[dataSourceType]
+	if !ok {
+		return driver, fmt.Errorf("%w: %s", errors.ErrUnsupportedDataSourceType, dataSourceType)
+	}
+	return driver, nil
+}
+
+// GetDataSourceTypes returns the registered data source types in sorted order.
+func GetDataSourceTypes() (dataSourceTypes []string) {
+	_dataSourceDriversMu.RLock()
+	defer _dataSourceDriversMu.RUnlock()
+	for dataSourceType := range _dataSourceDrivers {
+		dataSourceTypes = append(dataSourceTypes, dataSourceType)
+	}
+	sort.Strings(dataSourceTypes)
+	return dataSourceTypes
+}
+
+func OpenDataSource(dataSourceType string, opts *DataSourceOptions) (conn interface{}, err error) {
+	driver, err := GetDataSourceDriver(dataSourceType)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &DataSourceOptions{}
+	}
+	return driver.Factory(opts)
+}
+
+func GetDataSourceConnectionString(dataSourceType string, opts *DataSourceOptions) (connStr string, err error) {
+	driver, err := GetDataSourceDriver(dataSourceType)
+	if err != nil {
+		return "", err
+	}
+	if opts == nil {
+		opts = &DataSourceOptions{}
+	}
+	return driver.ConnectionString(opts)
+}
+
+func CheckDataSource(dataSourceType string, conn interface{}) (err error) {
+	driver, err := GetDataSourceDriver(dataSourceType)
+	if err != nil {
+		return err
+	}
+	return driver.HealthCheck(conn)
+}
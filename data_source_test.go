@@ -0,0 +1,46 @@
+package db
+
+import (
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRegisterDataSourceDriver(t *testing.T) {
+	dataSourceType := "test"
+	RegisterDataSourceDriver(dataSourceType, DataSourceDriver{
+		Factory: func(opts *DataSourceOptions) (conn interface{}, err error) {
+			return opts.Host, nil
+		},
+		ConnectionString: func(opts *DataSourceOptions) (connStr string, err error) {
+			return "test://" + opts.Host, nil
+		},
+		HealthCheck: func(conn interface{}) (err error) {
+			if conn != "localhost" {
+				return errors.ErrInvalidType
+			}
+			return nil
+		},
+	})
+	require.Contains(t, GetDataSourceTypes(), dataSourceType)
+
+	conn, err := OpenDataSource(dataSourceType, &DataSourceOptions{Host: "localhost"})
+	require.Nil(t, err)
+	require.Equal(t, "localhost", conn)
+	require.Nil(t, CheckDataSource(dataSourceType, conn))
+	require.NotNil(t, CheckDataSource(dataSourceType, "remote"))
+
+	connStr, err := GetDataSourceConnectionString(dataSourceType, &DataSourceOptions{Host: "localhost"})
+	require.Nil(t, err)
+	require.Equal(t, "test://localhost", connStr)
+
+	require.Panics(t, func() {
+		RegisterDataSourceDriver(dataSourceType, DataSourceDriver{})
+	})
+}
+
+func TestOpenDataSource_Unsupported(t *testing.T) {
+	_, err := OpenDataSource("unknown", nil)
+	require.True(t, goerrors.Is(err, errors.ErrUnsupportedDataSourceType))
+}
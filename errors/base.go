@@ -1,6 +1,7 @@
 package errors
 
 const (
-	errorPrefixMongo = "mongo"
-	errorPrefixRedis = "redis"
+	errorPrefixMongo   = "mongo"
+	errorPrefixRedis   = "redis"
+	errorPrefixGeneric = "generic"
 )
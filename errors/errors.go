@@ -3,8 +3,13 @@ package errors
 import "errors"
 
 var (
-	ErrInvalidType   = errors.New("invalid type")
-	ErrMissingValue  = errors.New("missing value")
-	ErrNoCursor      = errors.New("no cursor")
-	ErrAlreadyLocked = errors.New("already locked")
+	ErrInvalidType    = errors.New("invalid type")
+	ErrMissingValue   = errors.New("missing value")
+	ErrNoCursor       = errors.New("no cursor")
+	ErrNoClient       = errors.New("no client")
+	ErrAlreadyLocked  = errors.New("already locked")
+	ErrInvalidSort    = errors.New("invalid sort")
+	ErrInvalidOptions = errors.New("invalid options")
+
+	ErrUnsupportedDataSourceType = errors.New("unsupported data source type")
 )
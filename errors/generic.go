@@ -0,0 +1,35 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrorGenericInvalidOp    = NewGenericError("invalid op")
+	ErrorGenericInvalidValue = NewGenericError("invalid value")
+	ErrorGenericMissingKey   = NewGenericError("missing key")
+)
+
+func NewGenericError(msg string) (err error) {
+	return errors.New(fmt.Sprintf("%s: %s", errorPrefixGeneric, msg))
+}
+
+// OpError is returned when a list query condition fails validation.
+// Err is one of the ErrorGeneric* errors and can be matched with errors.Is.
+type OpError struct {
+	Key string
+	Op  string
+	Err error
+}
+
+func (e *OpError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s (op: %s)", e.Err.Error(), e.Op)
+	}
+	return fmt.Sprintf("%s (key: %s, op: %s)", e.Err.Error(), e.Key, e.Op)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,20 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrorMongoBulkWrite             = NewMongoError("bulk write failed")
+	ErrorMongoInvalidMigration      = NewMongoError("invalid migration")
+	ErrorMongoUnknownMigration      = NewMongoError("unknown migration")
+	ErrorMongoIrreversibleMigration = NewMongoError("irreversible migration")
+	ErrorMongoLocked                = NewMongoError("locked")
+	ErrorMongoVersionConflict       = NewMongoError("version conflict")
+	ErrorMongoInvalidPipeline       = NewMongoError("invalid pipeline")
+)
+
+func NewMongoError(msg string) (err error) {
+	return errors.New(fmt.Sprintf("%s: %s", errorPrefixMongo, msg))
+}
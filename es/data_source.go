@@ -0,0 +1,56 @@
+package es
+
+import (
+	"context"
+	"fmt"
+	"github.com/crawlab-team/crawlab-db"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"github.com/olivere/elastic/v7"
+)
+
+func init() {
+	db.RegisterDataSourceDriver(generic.DataSourceTypeElasticSearch, db.DataSourceDriver{
+		Factory:          newEsDataSource,
+		ConnectionString: getEsConnectionString,
+		HealthCheck:      checkEsDataSource,
+	})
+}
+
+func newEsDataSource(opts *db.DataSourceOptions) (conn interface{}, err error) {
+	connStr, err := getEsConnectionString(opts)
+	if err != nil {
+		return nil, err
+	}
+	esOpts := []elastic.ClientOptionFunc{
+		elastic.SetURL(connStr),
+		elastic.SetSniff(false),
+	}
+	if opts.Username != "" {
+		esOpts = append(esOpts, elastic.SetBasicAuth(opts.Username, opts.Password))
+	}
+	c, err := elastic.NewClient(esOpts...)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return c, nil
+}
+
+func getEsConnectionString(opts *db.DataSourceOptions) (connStr string, err error) {
+	if opts.Uri != "" {
+		return opts.Uri, nil
+	}
+	return fmt.Sprintf("http://%s:%s", opts.Host, opts.Port), nil
+}
+
+func checkEsDataSource(conn interface{}) (err error) {
+	c, ok := conn.(*elastic.Client)
+	if !ok {
+		return trace.TraceError(errors.ErrInvalidType)
+	}
+	if _, err := c.ClusterHealth().Do(context.Background()); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package es
+
+import (
+	"context"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"github.com/olivere/elastic/v7"
+	"reflect"
+	"strings"
+)
+
+// GetEsQuery translates a generic list query into a bool query. Conditions
+// are non-scoring filters, so keys should refer to keyword or numeric fields.
+// Regex patterns follow the lucene syntax and always match the whole value.
+func GetEsQuery(query generic.ListQuery) (q *elastic.BoolQuery, err error) {
+	if err := query.Validate(); err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getEsQuery(query), nil
+}
+
+func getEsQuery(query generic.ListQuery) (q *elastic.BoolQuery) {
+	q = elastic.NewBoolQuery()
+	for _, cond := range query {
+		switch cond.Op {
+		case generic.OpEqual:
+			if cond.Value == nil {
+				q.MustNot(elastic.NewExistsQuery(cond.Key))
+			} else {
+				q.Filter(elastic.NewTermQuery(cond.Key, cond.Value))
+			}
+		case generic.OpNotEqual:
+			if cond.Value == nil {
+				q.Filter(elastic.NewExistsQuery(cond.Key))
+			} else {
+				q.MustNot(elastic.NewTermQuery(cond.Key, cond.Value))
+			}
+		case generic.OpGreaterThan:
+			q.Filter(elastic.NewRangeQuery(cond.Key).Gt(cond.Value))
+		case generic.OpGreaterThanEqual:
+			q.Filter(elastic.NewRangeQuery(cond.Key).Gte(cond.Value))
+		case generic.OpLessThan:
+			q.Filter(elastic.NewRangeQuery(cond.Key).Lt(cond.Value))
+		case generic.OpLessThanEqual:
+			q.Filter(elastic.NewRangeQuery(cond.Key).Lte(cond.Value))
+		case generic.OpIn:
+			q.Filter(elastic.NewTermsQuery(cond.Key, getValues(cond.Value)...))
+		case generic.OpNotIn:
+			q.MustNot(elastic.NewTermsQuery(cond.Key, getValues(cond.Value)...))
+		case generic.OpExists:
+			if cond.Value.(bool) {
+				q.Filter(elastic.NewExistsQuery(cond.Key))
+			} else {
+				q.MustNot(elastic.NewExistsQuery(cond.Key))
+			}
+		case generic.OpContains:
+			q.Filter(elastic.NewWildcardQuery(cond.Key, "*"+escapeWildcard(cond.Value.(string))+"*"))
+		case generic.OpStartsWith:
+			q.Filter(elastic.NewWildcardQuery(cond.Key, escapeWildcard(cond.Value.(string))+"*"))
+		case generic.OpRegex:
+			q.Filter(elastic.NewRegexpQuery(cond.Key, cond.Value.(string)))
+		case generic.OpAnd:
+			for _, g := range cond.Value.([]generic.ListQuery) {
+				q.Filter(getEsQuery(g))
+			}
+		case generic.OpOr:
+			or := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+			for _, g := range cond.Value.([]generic.ListQuery) {
+				or.Should(getEsQuery(g))
+			}
+			q.Filter(or)
+		}
+	}
+	return q
+}
+
+// GetEsSorters translates generic list sorts into field sorters.
+func GetEsSorters(sorts []generic.ListSort) (sorters []elastic.Sorter, err error) {
+	for _, s := range sorts {
+		switch s.Direction {
+		case generic.SortDirectionAsc:
+			sorters = append(sorters, elastic.NewFieldSort(s.Key).Asc())
+		case generic.SortDirectionDesc:
+			sorters = append(sorters, elastic.NewFieldSort(s.Key).Desc())
+		default:
+			return nil, trace.TraceError(errors.ErrInvalidSort)
+		}
+	}
+	return sorters, nil
+}
+
+// GetEsSearchSource builds a search source with the bool query, sorters,
+// from and size translated from a generic list query and list options.
+func GetEsSearchSource(query generic.ListQuery, opts *generic.ListOptions) (src *elastic.SearchSource, err error) {
+	q, err := GetEsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	src = elastic.NewSearchSource().Query(q)
+	if opts == nil {
+		return src, nil
+	}
+	sorters, err := GetEsSorters(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if len(sorters) > 0 {
+		src.SortBy(sorters...)
+	}
+	if opts.Skip < 0 || opts.Limit < 0 {
+		return nil, trace.TraceError(errors.ErrInvalidOptions)
+	}
+	if opts.Skip > 0 {
+		src.From(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		src.Size(opts.Limit)
+	}
+	return src, nil
+}
+
+// Search runs a generic list query on the index with the default es client.
+// It returns errors.ErrNoClient if the default client could not be created.
+func Search(index string, query generic.ListQuery, opts *generic.ListOptions) (res *elastic.SearchResult, err error) {
+	doOnce.Do(InitEsClient)
+	return SearchWithClient(ctx, ESClient, index, query, opts)
+}
+
+// SearchWithClient runs a generic list query on the index with the client.
+func SearchWithClient(ctx context.Context, c *elastic.Client, index string, query generic.ListQuery, opts *generic.ListOptions) (res *elastic.SearchResult, err error) {
+	if c == nil {
+		return nil, trace.TraceError(errors.ErrNoClient)
+	}
+	src, err := GetEsSearchSource(query, opts)
+	if err != nil {
+		return nil, err
+	}
+	res, err = c.Search(index).SearchSource(src).Do(ctx)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return res, nil
+}
+
+func getValues(value interface{}) (values []interface{}) {
+	v := reflect.ValueOf(value)
+	values = make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		values = append(values, v.Index(i).Interface())
+	}
+	return values
+}
+
+func escapeWildcard(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(value)
+}
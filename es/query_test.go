@@ -0,0 +1,79 @@
+package es
+
+import (
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetEsQuery(t *testing.T) {
+	q, err := GetEsQuery(generic.ListQuery{
+		{Key: "level", Op: generic.OpEqual, Value: "error"},
+		{Key: "node", Op: generic.OpNotEqual, Value: "master"},
+		{Key: "ts", Op: generic.OpGreaterThanEqual, Value: 100},
+		{Key: "tags", Op: generic.OpIn, Value: []string{"a", "b"}},
+		{Key: "error", Op: generic.OpExists, Value: false},
+		{Key: "msg", Op: generic.OpContains, Value: "time*out"},
+		{Op: generic.OpOr, Value: []generic.ListQuery{
+			{{Key: "spider", Op: generic.OpStartsWith, Value: "news"}},
+			{{Key: "spider", Op: generic.OpRegex, Value: "sp.*"}},
+		}},
+	})
+	require.Nil(t, err)
+
+	src, err := q.Source()
+	require.Nil(t, err)
+	data, err := json.Marshal(src)
+	require.Nil(t, err)
+	require.JSONEq(t, `{"bool": {
+		"filter": [
+			{"term": {"level": "error"}},
+			{"range": {"ts": {"from": 100, "include_lower": true, "include_upper": true, "to": null}}},
+			{"terms": {"tags": ["a", "b"]}},
+			{"wildcard": {"msg": {"wildcard": "*time\\*out*"}}},
+			{"bool": {
+				"minimum_should_match": "1",
+				"should": [
+					{"bool": {"filter": {"wildcard": {"spider": {"wildcard": "news*"}}}}},
+					{"bool": {"filter": {"regexp": {"spider": {"value": "sp.*"}}}}}
+				]
+			}}
+		],
+		"must_not": [
+			{"term": {"node": "master"}},
+			{"exists": {"field": "error"}}
+		]
+	}}`, string(data))
+}
+
+func TestGetEsSearchSource(t *testing.T) {
+	src, err := GetEsSearchSource(nil, &generic.ListOptions{
+		Skip:  10,
+		Limit: 5,
+		Sort:  []generic.ListSort{{Key: "ts", Direction: generic.SortDirectionDesc}},
+	})
+	require.Nil(t, err)
+
+	s, err := src.Source()
+	require.Nil(t, err)
+	data, err := json.Marshal(s)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"from": 10,
+		"size": 5,
+		"query": {"bool": {}},
+		"sort": [{"ts": {"order": "desc"}}]
+	}`, string(data))
+
+	_, err = GetEsSearchSource(nil, &generic.ListOptions{Sort: []generic.ListSort{{Key: "ts"}}})
+	require.NotNil(t, err)
+}
+
+func TestSearchWithClient_NoClient(t *testing.T) {
+	_, err := SearchWithClient(context.Background(), nil, "logs", nil, nil)
+	require.True(t, goerrors.Is(err, errors.ErrNoClient))
+}
@@ -4,5 +4,5 @@ const (
 	DataSourceTypeMongo         = "mongo"
 	DataSourceTypeMysql         = "mysql"
 	DataSourceTypePostgres      = "postgres"
-	DataSourceTypeElasticSearch = "postgres"
+	DataSourceTypeElasticSearch = "elasticsearch"
 )
@@ -3,5 +3,27 @@ package generic
 type Op string
 
 const (
-	OpEqual = "eq"
+	OpEqual            = "eq"
+	OpNotEqual         = "ne"
+	OpGreaterThan      = "gt"
+	OpGreaterThanEqual = "gte"
+	OpLessThan         = "lt"
+	OpLessThanEqual    = "lte"
+	OpIn               = "in"
+	OpNotIn            = "nin"
+	OpExists           = "exists"
+	OpContains         = "contains"
+	OpStartsWith       = "startsWith"
+	OpRegex            = "regex"
+
+	// OpAnd and OpOr have no key and take a []ListQuery value, each
+	// ListQuery being a group of conditions that must all match.
+	OpAnd = "and"
+	OpOr  = "or"
 )
+
+// IsGroup returns true if the op combines nested list queries instead of
+// comparing a key with a value.
+func (op Op) IsGroup() bool {
+	return op == OpAnd || op == OpOr
+}
@@ -0,0 +1,160 @@
+package generic
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// Validate checks every condition of the list query, including nested
+// and/or groups, and returns the first *errors.OpError found.
+func (q ListQuery) Validate() (err error) {
+	for _, cond := range q {
+		if err := cond.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks that the op is known and that its value has a type the
+// op can be applied to.
+func (cond ListQueryCondition) Validate() (err error) {
+	op := Op(cond.Op)
+
+	// and/or groups
+	if op.IsGroup() {
+		if cond.Key != "" {
+			return cond.newError(errors.ErrorGenericInvalidOp)
+		}
+		groups, ok := cond.Value.([]ListQuery)
+		if !ok || len(groups) == 0 {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+		for _, g := range groups {
+			if err := g.Validate(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// key is required for comparison ops
+	if cond.Key == "" {
+		return cond.newError(errors.ErrorGenericMissingKey)
+	}
+
+	switch op {
+	case OpEqual, OpNotEqual:
+		if isDocument(cond.Value) {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+	case OpGreaterThan, OpGreaterThanEqual, OpLessThan, OpLessThanEqual:
+		if !isComparable(cond.Value) {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+	case OpIn, OpNotIn:
+		if !isList(cond.Value) {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+		v := reflect.ValueOf(cond.Value)
+		for i := 0; i < v.Len(); i++ {
+			if isDocument(v.Index(i).Interface()) {
+				return cond.newError(errors.ErrorGenericInvalidValue)
+			}
+		}
+	case OpExists:
+		if _, ok := cond.Value.(bool); !ok {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+	case OpContains, OpStartsWith:
+		if _, ok := cond.Value.(string); !ok {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+	case OpRegex:
+		pattern, ok := cond.Value.(string)
+		if !ok {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return cond.newError(errors.ErrorGenericInvalidValue)
+		}
+	default:
+		return cond.newError(errors.ErrorGenericInvalidOp)
+	}
+
+	return nil
+}
+
+func (cond ListQueryCondition) newError(err error) *errors.OpError {
+	return &errors.OpError{
+		Key: cond.Key,
+		Op:  cond.Op,
+		Err: err,
+	}
+}
+
+// isComparable returns true for numbers, strings and times, which can be
+// used with range ops.
+func isComparable(value interface{}) bool {
+	if _, ok := value.(time.Time); ok {
+		return true
+	}
+	if value == nil {
+		return false
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.String:
+		return true
+	default:
+		return false
+	}
+}
+
+// isList returns true for slices and arrays, which can be used with in/nin.
+func isList(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// isDocument returns true for maps, structs and slices of structs such as
+// bson.D, which data sources may interpret as operator expressions, e.g.
+// {"$ne": v} as the value of an equality.
+func isDocument(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return isDocumentType(t.Elem())
+	default:
+		return isDocumentType(t)
+	}
+}
+
+func isDocumentType(t reflect.Type) bool {
+	if t == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package generic
+
+import (
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestListQuery_Validate(t *testing.T) {
+	valid := ListQuery{
+		{Key: "status", Op: OpEqual, Value: "running"},
+		{Key: "status", Op: OpNotEqual, Value: nil},
+		{Key: "count", Op: OpGreaterThan, Value: 1},
+		{Key: "count", Op: OpLessThanEqual, Value: 1.5},
+		{Key: "create_ts", Op: OpGreaterThanEqual, Value: time.Now()},
+		{Key: "tags", Op: OpIn, Value: []string{"a", "b"}},
+		{Key: "create_ts", Op: OpIn, Value: []time.Time{time.Now()}},
+		{Key: "create_ts", Op: OpEqual, Value: time.Now()},
+		{Key: "tags", Op: OpNotIn, Value: [2]int{1, 2}},
+		{Key: "error", Op: OpExists, Value: false},
+		{Key: "name", Op: OpContains, Value: "spider"},
+		{Key: "name", Op: OpStartsWith, Value: "sp"},
+		{Key: "name", Op: OpRegex, Value: "^sp.*r$"},
+		{Op: OpOr, Value: []ListQuery{
+			{{Key: "a", Op: OpEqual, Value: 1}},
+			{{Op: OpAnd, Value: []ListQuery{{{Key: "b", Op: OpExists, Value: true}}}}},
+		}},
+	}
+	require.Nil(t, valid.Validate())
+}
+
+func TestListQuery_Validate_Error(t *testing.T) {
+	cases := []struct {
+		cond ListQueryCondition
+		err  error
+	}{
+		{ListQueryCondition{Key: "a", Op: "like", Value: "x"}, errors.ErrorGenericInvalidOp},
+		{ListQueryCondition{Op: OpEqual, Value: "x"}, errors.ErrorGenericMissingKey},
+		{ListQueryCondition{Key: "a", Op: OpGreaterThan, Value: []int{1}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpLessThan, Value: nil}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpIn, Value: 1}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpExists, Value: "true"}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpContains, Value: 1}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpRegex, Value: "("}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpAnd, Value: []ListQuery{{}}}, errors.ErrorGenericInvalidOp},
+		{ListQueryCondition{Op: OpOr, Value: ListQuery{}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Op: OpOr, Value: []ListQuery{}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Op: OpOr, Value: []ListQuery{{{Key: "a", Op: OpIn, Value: "x"}}}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpEqual, Value: map[string]interface{}{"$ne": "x"}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpNotEqual, Value: struct{ Ne string }{"x"}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpEqual, Value: []struct{ Key string }{{"$ne"}}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpIn, Value: []interface{}{"x", map[string]interface{}{"$ne": "x"}}}, errors.ErrorGenericInvalidValue},
+		{ListQueryCondition{Key: "a", Op: OpNotIn, Value: []map[string]int{{"$gt": 1}}}, errors.ErrorGenericInvalidValue},
+	}
+	for _, c := range cases {
+		err := ListQuery{c.cond}.Validate()
+		require.NotNil(t, err)
+		require.True(t, goerrors.Is(err, c.err), err.Error())
+		var opErr *errors.OpError
+		require.True(t, goerrors.As(err, &opErr))
+	}
+}
@@ -1,6 +1,9 @@
 package db
 
-import "time"
+import (
+	"github.com/crawlab-team/crawlab-db/generic"
+	"time"
+)
 
 type RedisClient interface {
 	Ping() (err error)
@@ -37,3 +40,12 @@ type RedisClient interface {
 	SetBackoffMaxInterval(interval time.Duration)
 	SetTimeout(timeout int)
 }
+
+type Repository interface {
+	Insert(doc interface{}) (id interface{}, err error)
+	Get(id interface{}, result interface{}) (err error)
+	List(query generic.ListQuery, opts *generic.ListOptions, results interface{}) (err error)
+	Count(query generic.ListQuery) (total int, err error)
+	Update(query generic.ListQuery, values map[string]interface{}) (err error)
+	Delete(query generic.ListQuery) (err error)
+}
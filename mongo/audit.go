@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+const (
+	CreatedTsField = "created_ts"
+	CreatedByField = "created_by"
+	UpdatedTsField = "updated_ts"
+	UpdatedByField = "updated_by"
+)
+
+type auditUserContextKey struct{}
+
+// ContextWithUser returns a copy of ctx carrying the user stamped in the
+// audit fields by collections with WithColAudit, e.g. the user id.
+func ContextWithUser(ctx context.Context, user interface{}) context.Context {
+	return context.WithValue(ctx, auditUserContextKey{}, user)
+}
+
+// UserFromContext returns the user set by ContextWithUser, or nil.
+func UserFromContext(ctx context.Context) (user interface{}) {
+	return ctx.Value(auditUserContextKey{})
+}
+
+// getAuditFields returns the ts and by fields stamped with the current time
+// and the user of the context, if any.
+func (col *Col) getAuditFields(tsField, byField string) (fields bson.D) {
+	fields = bson.D{{Key: tsField, Value: time.Now()}}
+	if user := UserFromContext(col.ctx); user != nil {
+		fields = append(fields, bson.E{Key: byField, Value: user})
+	}
+	return fields
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+	"time"
+)
+
+type TestAuditDocument struct {
+	Id        primitive.ObjectID `bson:"_id,omitempty"`
+	Key       string             `bson:"key"`
+	CreatedTs time.Time          `bson:"created_ts"`
+	CreatedBy string             `bson:"created_by"`
+	UpdatedTs time.Time          `bson:"updated_ts"`
+	UpdatedBy string             `bson:"updated_by"`
+}
+
+func getBsonDKeys(d bson.D) (keys []string) {
+	for _, e := range d {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestCol_getUpdate(t *testing.T) {
+	col := (&Col{ctx: context.Background(), audit: true}).WithContext(ContextWithUser(context.Background(), "user"))
+
+	// bson.M
+	update, err := col.getUpdate(bson.M{"$set": bson.M{"key": "value"}, "$inc": bson.M{"value": 1}}, false)
+	require.Nil(t, err)
+	d := update.(bson.D)
+	require.ElementsMatch(t, []string{"$set", "$inc"}, getBsonDKeys(d))
+	for _, e := range d {
+		if e.Key == "$set" {
+			require.Equal(t, []string{"key", UpdatedTsField, UpdatedByField}, getBsonDKeys(e.Value.(bson.D)))
+			require.Equal(t, "user", e.Value.(bson.D)[2].Value)
+		}
+	}
+
+	// struct in $set, upsert
+	update, err = col.getUpdate(bson.D{{Key: "$set", Value: TestDocument{Key: "value"}}}, true)
+	require.Nil(t, err)
+	d = update.(bson.D)
+	require.Equal(t, []string{"$set", "$setOnInsert"}, getBsonDKeys(d))
+	require.Equal(t, []string{"key", "value", "tags", UpdatedTsField, UpdatedByField}, getBsonDKeys(d[0].Value.(bson.D)))
+	require.Equal(t, []string{CreatedTsField, CreatedByField}, getBsonDKeys(d[1].Value.(bson.D)))
+
+	// without $set, fields updated by other operators are left out
+	update, err = col.getUpdate(bson.M{"$unset": bson.M{UpdatedByField: ""}}, false)
+	require.Nil(t, err)
+	d = update.(bson.D)
+	require.Equal(t, []string{"$unset", "$set"}, getBsonDKeys(d))
+	require.Equal(t, []string{UpdatedTsField}, getBsonDKeys(d[1].Value.(bson.D)))
+
+	// pipeline
+	update, err = col.getUpdate(mongo.Pipeline{{{Key: "$set", Value: bson.M{"key": "value"}}}}, false)
+	require.Nil(t, err)
+	require.Len(t, update, 2)
+
+	// no user
+	col = col.WithContext(context.Background())
+	update, err = col.getUpdate(bson.M{"$set": bson.M{"key": "value"}}, false)
+	require.Nil(t, err)
+	require.Equal(t, []string{"key", UpdatedTsField}, getBsonDKeys(update.(bson.D)[0].Value.(bson.D)))
+
+	// disabled
+	col = &Col{ctx: context.Background()}
+	u := bson.M{"$set": bson.M{"key": "value"}}
+	update, err = col.getUpdate(u, false)
+	require.Nil(t, err)
+	require.Equal(t, u, update)
+}
+
+func TestCol_Audit(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColAudit()).
+		WithContext(ContextWithUser(context.Background(), "creator"))
+
+	// insert
+	id, err := col.Insert(&TestAuditDocument{Key: "a"})
+	require.Nil(t, err)
+	ids, err := col.InsertMany([]interface{}{bson.M{"key": "b"}})
+	require.Nil(t, err)
+	var doc TestAuditDocument
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "creator", doc.CreatedBy)
+	require.False(t, doc.CreatedTs.IsZero())
+	require.True(t, doc.UpdatedTs.IsZero())
+	err = col.FindId(ids[0]).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "creator", doc.CreatedBy)
+
+	// update
+	col = col.WithContext(ContextWithUser(context.Background(), "updater"))
+	err = col.UpdateId(id, bson.M{"$set": bson.M{"key": "a2"}})
+	require.Nil(t, err)
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "a2", doc.Key)
+	require.Equal(t, "creator", doc.CreatedBy)
+	require.Equal(t, "updater", doc.UpdatedBy)
+	require.False(t, doc.UpdatedTs.IsZero())
+
+	// upsert
+	err = col.UpdateWithOptions(bson.M{"key": "c"}, bson.M{"$set": bson.M{"key": "c"}}, options.Update().SetUpsert(true))
+	require.Nil(t, err)
+	err = col.Find(bson.M{"key": "c"}, nil).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "updater", doc.CreatedBy)
+	require.Equal(t, "updater", doc.UpdatedBy)
+
+	// replace
+	err = col.ReplaceId(ids[0], TestAuditDocument{Key: "b2", CreatedBy: "creator"})
+	require.Nil(t, err)
+	err = col.FindId(ids[0]).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "b2", doc.Key)
+	require.Equal(t, "updater", doc.UpdatedBy)
+
+	cleanupColTest(to)
+}
@@ -0,0 +1,176 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const DefaultBulkWriteBatchSize = 1000
+
+// BulkWrite collects mixed write operations on a collection and sends them
+// with Execute, in batches of BatchSize operations.
+type BulkWrite struct {
+	col       *Col
+	models    []mongo.WriteModel
+	ordered   bool
+	batchSize int
+}
+
+// BulkWriteResult sums up the results of all batches. Indexes of UpsertedIds
+// and Errors refer to the order in which operations were added.
+type BulkWriteResult struct {
+	InsertedCount int64
+	MatchedCount  int64
+	ModifiedCount int64
+	UpsertedCount int64
+	DeletedCount  int64
+	UpsertedIds   map[int]interface{}
+	Errors        []BulkWriteItemError
+}
+
+type BulkWriteItemError struct {
+	Index   int
+	Code    int
+	Message string
+}
+
+// Ordered sets whether operations are executed in order and stop at the
+// first failed operation (the default), or all attempted in any order.
+func (bw *BulkWrite) Ordered(ordered bool) *BulkWrite {
+	bw.ordered = ordered
+	return bw
+}
+
+func (bw *BulkWrite) BatchSize(size int) *BulkWrite {
+	bw.batchSize = size
+	return bw
+}
+
+func (bw *BulkWrite) Insert(docs ...interface{}) *BulkWrite {
+	for _, doc := range docs {
+		bw.models = append(bw.models, mongo.NewInsertOneModel().SetDocument(doc))
+	}
+	return bw
+}
+
+func (bw *BulkWrite) UpdateOne(query bson.M, update interface{}) *BulkWrite {
+	bw.models = append(bw.models, mongo.NewUpdateOneModel().SetFilter(query).SetUpdate(update))
+	return bw
+}
+
+func (bw *BulkWrite) UpdateMany(query bson.M, update interface{}) *BulkWrite {
+	bw.models = append(bw.models, mongo.NewUpdateManyModel().SetFilter(query).SetUpdate(update))
+	return bw
+}
+
+func (bw *BulkWrite) Upsert(query bson.M, update interface{}) *BulkWrite {
+	bw.models = append(bw.models, mongo.NewUpdateOneModel().SetFilter(query).SetUpdate(update).SetUpsert(true))
+	return bw
+}
+
+func (bw *BulkWrite) ReplaceOne(query bson.M, doc interface{}) *BulkWrite {
+	bw.models = append(bw.models, mongo.NewReplaceOneModel().SetFilter(query).SetReplacement(doc))
+	return bw
+}
+
+func (bw *BulkWrite) UpsertReplace(query bson.M, doc interface{}) *BulkWrite {
+	bw.models = append(bw.models, mongo.NewReplaceOneModel().SetFilter(query).SetReplacement(doc).SetUpsert(true))
+	return bw
+}
+
+// DeleteOne deletes the first document matching query. In soft delete mode,
+// the document is marked as deleted and counted as matched and modified
+// instead of deleted.
+func (bw *BulkWrite) DeleteOne(query bson.M) *BulkWrite {
+	if bw.col.softDelete {
+		bw.models = append(bw.models, mongo.NewUpdateOneModel().SetFilter(bw.col.getQuery(query)).SetUpdate(bw.col.getSoftDeleteUpdate()))
+		return bw
+	}
+	bw.models = append(bw.models, mongo.NewDeleteOneModel().SetFilter(query))
+	return bw
+}
+
+// DeleteMany deletes the documents matching query. In soft delete mode, the
+// documents are marked as deleted and counted as matched and modified instead
+// of deleted.
+func (bw *BulkWrite) DeleteMany(query bson.M) *BulkWrite {
+	if bw.col.softDelete {
+		bw.models = append(bw.models, mongo.NewUpdateManyModel().SetFilter(bw.col.getQuery(query)).SetUpdate(bw.col.getSoftDeleteUpdate()))
+		return bw
+	}
+	bw.models = append(bw.models, mongo.NewDeleteManyModel().SetFilter(query))
+	return bw
+}
+
+// Model adds raw driver write models, which are sent as is regardless of the
+// options of the collection.
+func (bw *BulkWrite) Model(models ...mongo.WriteModel) *BulkWrite {
+	bw.models = append(bw.models, models...)
+	return bw
+}
+
+func (bw *BulkWrite) Len() int {
+	return len(bw.models)
+}
+
+// Execute sends the operations. If some operations fail, the result holds
+// the counts of the successful ones and the per-item errors, and
+// errors.ErrorMongoBulkWrite is returned. Other errors abort the execution.
+func (bw *BulkWrite) Execute() (res *BulkWriteResult, err error) {
+	res = &BulkWriteResult{
+		UpsertedIds: map[int]interface{}{},
+	}
+	batchSize := bw.batchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBulkWriteBatchSize
+	}
+	opts := options.BulkWrite().SetOrdered(bw.ordered)
+
+	for start := 0; start < len(bw.models); start += batchSize {
+		end := start + batchSize
+		if end > len(bw.models) {
+			end = len(bw.models)
+		}
+
+		// execute batch
+		_res, err := bw.col.c.BulkWrite(bw.col.ctx, bw.models[start:end], opts)
+		if _res != nil {
+			res.add(start, _res)
+		}
+		if err != nil {
+			bwe, ok := err.(mongo.BulkWriteException)
+			if !ok || bwe.WriteConcernError != nil {
+				return res, trace.TraceError(err)
+			}
+			for _, e := range bwe.WriteErrors {
+				res.Errors = append(res.Errors, BulkWriteItemError{
+					Index:   start + e.Index,
+					Code:    e.Code,
+					Message: e.Message,
+				})
+			}
+			if bw.ordered {
+				break
+			}
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return res, trace.TraceError(errors.ErrorMongoBulkWrite)
+	}
+	return res, nil
+}
+
+func (res *BulkWriteResult) add(offset int, _res *mongo.BulkWriteResult) {
+	res.InsertedCount += _res.InsertedCount
+	res.MatchedCount += _res.MatchedCount
+	res.ModifiedCount += _res.ModifiedCount
+	res.UpsertedCount += _res.UpsertedCount
+	res.DeletedCount += _res.DeletedCount
+	for i, id := range _res.UpsertedIDs {
+		res.UpsertedIds[offset+int(i)] = id
+	}
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	goerrors "errors"
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestCol_BulkWrite(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	n := 10
+	bw := to.col.BulkWrite().BatchSize(3)
+	for i := 0; i < n; i++ {
+		bw.Insert(bson.M{"key": fmt.Sprintf("value-%d", i), "value": i})
+	}
+	bw.UpdateMany(bson.M{"value": bson.M{"$lt": 5}}, bson.M{"$set": bson.M{"tags": []string{"low"}}}).
+		Upsert(bson.M{"key": "value-new"}, bson.M{"$set": bson.M{"value": n}}).
+		ReplaceOne(bson.M{"key": "value-9"}, bson.M{"key": "value-9", "value": 99}).
+		DeleteOne(bson.M{"key": "value-0"})
+	require.Equal(t, n+4, bw.Len())
+
+	res, err := bw.Execute()
+	require.Nil(t, err)
+	require.Equal(t, int64(n), res.InsertedCount)
+	require.Equal(t, int64(6), res.MatchedCount)
+	require.Equal(t, int64(6), res.ModifiedCount)
+	require.Equal(t, int64(1), res.UpsertedCount)
+	require.Equal(t, int64(1), res.DeletedCount)
+	require.Contains(t, res.UpsertedIds, n+1)
+
+	total, err := to.col.Count(nil)
+	require.Nil(t, err)
+	require.Equal(t, n, total)
+
+	cleanupColTest(to)
+}
+
+func TestCol_BulkWrite_SoftDelete(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColSoftDelete())
+
+	_, err = col.InsertMany([]interface{}{
+		bson.M{"key": "a"},
+		bson.M{"key": "b"},
+		bson.M{"key": "b"},
+	})
+	require.Nil(t, err)
+
+	res, err := col.BulkWrite().
+		DeleteOne(bson.M{"key": "a"}).
+		DeleteMany(bson.M{"key": "b"}).
+		Execute()
+	require.Nil(t, err)
+	require.Equal(t, int64(0), res.DeletedCount)
+	require.Equal(t, int64(3), res.ModifiedCount)
+
+	total, err := col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+	total, err = to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 3, total)
+
+	cleanupColTest(to)
+}
+
+func TestCol_BulkWrite_Errors(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	id, err := to.col.Insert(bson.M{"key": "value"})
+	require.Nil(t, err)
+
+	// ordered: stops at the duplicate key
+	res, err := to.col.BulkWrite().
+		Insert(bson.M{"key": "value-1"}, bson.M{"_id": id}, bson.M{"key": "value-2"}).
+		Execute()
+	require.True(t, goerrors.Is(err, errors.ErrorMongoBulkWrite))
+	require.Equal(t, int64(1), res.InsertedCount)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, 1, res.Errors[0].Index)
+
+	// unordered: attempts all operations across batches
+	res, err = to.col.BulkWrite().Ordered(false).BatchSize(1).
+		Insert(bson.M{"_id": id}, bson.M{"key": "value-3"}, bson.M{"_id": id}, bson.M{"key": "value-4"}).
+		Execute()
+	require.True(t, goerrors.Is(err, errors.ErrorMongoBulkWrite))
+	require.Equal(t, int64(2), res.InsertedCount)
+	require.Len(t, res.Errors, 2)
+	require.Equal(t, 0, res.Errors[0].Index)
+	require.Equal(t, 2, res.Errors[1].Index)
+
+	cleanupColTest(to)
+}
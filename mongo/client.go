@@ -2,25 +2,119 @@ package mongo
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/apex/log"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/crawlab-team/crawlab-db/errors"
 	"github.com/crawlab-team/go-trace"
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"os"
+	"strconv"
 	"sync"
+	"time"
 )
 
 var AppName = "crawlab-db"
 
+const (
+	DefaultConnectRetryMaxElapsedTime = 30 * time.Second
+	DefaultConnectRetryMaxInterval    = 5 * time.Second
+)
+
+// _clientMap holds the clients by key (see getClientKey) and is guarded by _mu.
 var _clientMap = map[string]*mongo.Client{}
 var _mu sync.Mutex
 
+// GetMongoClient returns the client of the options, creating it on first use.
+// Clients are cached by name if WithClientName is given, and by a hash of the
+// options otherwise; the other options of a named client only apply when it
+// is created.
 func GetMongoClient(opts ...ClientOption) (c *mongo.Client, err error) {
 	// client options
-	_opts := &ClientOptions{}
+	_opts := getClientOptions(opts...)
+	key, err := getClientKey(_opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt to get client by key
+	_mu.Lock()
+	c, ok := _clientMap[key]
+	_mu.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	// create new mongo client without holding the lock, as connecting may
+	// take a while
+	c, err = newMongoClient(_opts.Context, _opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// add to map, unless created concurrently
+	_mu.Lock()
+	defer _mu.Unlock()
+	if _c, ok := _clientMap[key]; ok {
+		_ = c.Disconnect(context.Background())
+		return _c, nil
+	}
+	_clientMap[key] = c
+
+	return c, nil
+}
+
+// DisconnectMongoClient disconnects the client of the options, if created,
+// and removes it from the cache.
+func DisconnectMongoClient(ctx context.Context, opts ...ClientOption) (err error) {
+	key, err := getClientKey(getClientOptions(opts...))
+	if err != nil {
+		return err
+	}
+
+	_mu.Lock()
+	c, ok := _clientMap[key]
+	delete(_clientMap, key)
+	_mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := c.Disconnect(ctx); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// CloseAllMongoClients disconnects all created clients and empties the cache,
+// e.g. for a graceful shutdown. It returns the first error after attempting
+// to disconnect every client.
+func CloseAllMongoClients(ctx context.Context) (err error) {
+	_mu.Lock()
+	clients := _clientMap
+	_clientMap = map[string]*mongo.Client{}
+	_mu.Unlock()
+
+	for _, c := range clients {
+		if _err := c.Disconnect(ctx); _err != nil && err == nil {
+			err = trace.TraceError(_err)
+		}
+	}
+	return err
+}
+
+func getClientOptions(opts ...ClientOption) (_opts *ClientOptions) {
+	_opts = &ClientOptions{}
 	for _, op := range opts {
 		op(_opts)
 	}
@@ -66,37 +160,166 @@ func GetMongoClient(opts ...ClientOption) (c *mongo.Client, err error) {
 	if _opts.AuthMechanismProperties == nil {
 		_opts.AuthMechanismProperties = viper.GetStringMapString("mongo.authMechanismProperties")
 	}
-
-	// client options key json string
-	_optsKeyBytes, err := json.Marshal(_opts)
-	if err != nil {
-		return nil, trace.TraceError(err)
+	if !_opts.Tls {
+		_opts.Tls = viper.GetBool("mongo.tls")
+	}
+	if _opts.TlsCaFile == "" {
+		_opts.TlsCaFile = viper.GetString("mongo.tlsCaFile")
+	}
+	if _opts.TlsCertFile == "" {
+		_opts.TlsCertFile = viper.GetString("mongo.tlsCertFile")
+	}
+	if _opts.TlsKeyFile == "" {
+		_opts.TlsKeyFile = viper.GetString("mongo.tlsKeyFile")
+	}
+	if !_opts.TlsInsecure {
+		_opts.TlsInsecure = viper.GetBool("mongo.tlsInsecure")
+	}
+	if _opts.ReplicaSet == "" {
+		_opts.ReplicaSet = viper.GetString("mongo.replicaSet")
+	}
+	if _opts.ReadPreference == "" {
+		_opts.ReadPreference = viper.GetString("mongo.readPreference")
+	}
+	if _opts.ReadConcern == "" {
+		_opts.ReadConcern = viper.GetString("mongo.readConcern")
+	}
+	if _opts.WriteConcern == "" {
+		_opts.WriteConcern = viper.GetString("mongo.writeConcern")
+	}
+	if _opts.ConnectTimeout == 0 {
+		_opts.ConnectTimeout = viper.GetDuration("mongo.connectTimeout")
+	}
+	if _opts.ServerSelectionTimeout == 0 {
+		_opts.ServerSelectionTimeout = viper.GetDuration("mongo.serverSelectionTimeout")
+	}
+	if _opts.SocketTimeout == 0 {
+		_opts.SocketTimeout = viper.GetDuration("mongo.socketTimeout")
+	}
+	if _opts.MinPoolSize == 0 {
+		_opts.MinPoolSize = viper.GetUint64("mongo.minPoolSize")
+	}
+	if _opts.MaxPoolSize == 0 {
+		_opts.MaxPoolSize = viper.GetUint64("mongo.maxPoolSize")
+	}
+	if len(_opts.Compressors) == 0 {
+		_opts.Compressors = viper.GetStringSlice("mongo.compressors")
+	}
+	if _opts.RetryReads == nil && viper.IsSet("mongo.retryReads") {
+		value := viper.GetBool("mongo.retryReads")
+		_opts.RetryReads = &value
 	}
-	_optsKey := string(_optsKeyBytes)
+	if _opts.RetryWrites == nil && viper.IsSet("mongo.retryWrites") {
+		value := viper.GetBool("mongo.retryWrites")
+		_opts.RetryWrites = &value
+	}
+	if _opts.DirectConnection == nil && viper.IsSet("mongo.directConnection") {
+		value := viper.GetBool("mongo.directConnection")
+		_opts.DirectConnection = &value
+	}
+	if _opts.ConnectRetryMaxElapsedTime == 0 {
+		_opts.ConnectRetryMaxElapsedTime = viper.GetDuration("mongo.connectRetryMaxElapsedTime")
+		if _opts.ConnectRetryMaxElapsedTime == 0 {
+			_opts.ConnectRetryMaxElapsedTime = DefaultConnectRetryMaxElapsedTime
+		}
+	}
+	if _opts.ConnectRetryMaxInterval == 0 {
+		_opts.ConnectRetryMaxInterval = viper.GetDuration("mongo.connectRetryMaxInterval")
+		if _opts.ConnectRetryMaxInterval == 0 {
+			_opts.ConnectRetryMaxInterval = DefaultConnectRetryMaxInterval
+		}
+	}
+	if _opts.ConnectRetryMaxAttempts == 0 {
+		_opts.ConnectRetryMaxAttempts = viper.GetInt("mongo.connectRetryMaxAttempts")
+	}
+	if !_opts.Ping {
+		_opts.Ping = viper.GetBool("mongo.ping")
+	}
+	return _opts
+}
 
-	// attempt to get client by client options
-	c, ok := _clientMap[_optsKey]
-	if ok {
-		return c, nil
+// getClientKey returns the name of a named client, or a sha256 hash of the
+// options, so that no secret is kept in the key.
+func getClientKey(_opts *ClientOptions) (key string, err error) {
+	if _opts.Name != "" {
+		return "name:" + _opts.Name, nil
+	}
+	_optsBytes, err := json.Marshal(_opts)
+	if err != nil {
+		return "", trace.TraceError(err)
 	}
+	hash := sha256.Sum256(_optsBytes)
+	return "hash:" + hex.EncodeToString(hash[:]), nil
+}
 
-	// create new mongo client
-	c, err = newMongoClient(_opts.Context, _opts)
+func newMongoClient(ctx context.Context, _opts *ClientOptions) (c *mongo.Client, err error) {
+	// mongo client options
+	mongoOpts, err := getMongoClientOptions(_opts)
 	if err != nil {
 		return nil, err
 	}
 
-	// add to map
-	_mu.Lock()
-	_clientMap[_optsKey] = c
-	_mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// attempt to connect with retry
+	err = backoff.RetryNotify(func() error {
+		c, err = mongo.NewClient(mongoOpts)
+		if err != nil {
+			// invalid options
+			return backoff.Permanent(err)
+		}
+		if err := c.Connect(ctx); err != nil {
+			return err
+		}
+		if !_opts.Ping {
+			return nil
+		}
+		if err := c.Ping(ctx, nil); err != nil {
+			_ = c.Disconnect(context.Background())
+			return err
+		}
+		return nil
+	}, newConnectBackOff(ctx, _opts), func(err error, d time.Duration) {
+		log.WithError(err).Warnf("waiting for connect mongo database, after %f seconds try again.", d.Seconds())
+	})
+	if err != nil {
+		return nil, trace.TraceError(fmt.Errorf("connect mongo database: %w", err))
+	}
 
 	return c, nil
 }
 
-func newMongoClient(ctx context.Context, _opts *ClientOptions) (c *mongo.Client, err error) {
-	// mongo client options
-	mongoOpts := &options.ClientOptions{
+func newConnectBackOff(ctx context.Context, _opts *ClientOptions) (b backoff.BackOff) {
+	bp := backoff.NewExponentialBackOff()
+	bp.MaxElapsedTime = _opts.ConnectRetryMaxElapsedTime
+	bp.MaxInterval = _opts.ConnectRetryMaxInterval
+	b = bp
+	if _opts.ConnectRetryMaxAttempts > 0 {
+		b = backoff.WithMaxRetries(b, uint64(_opts.ConnectRetryMaxAttempts-1))
+	}
+	return backoff.WithContext(b, ctx)
+}
+
+// InitMongo connects the default client and checks the connection, so that
+// a bad configuration or an unreachable server fails at startup rather than
+// on first use.
+func InitMongo() (err error) {
+	c, err := GetMongoClient()
+	if err != nil {
+		return err
+	}
+	if err := c.Ping(context.Background(), nil); err != nil {
+		return trace.TraceError(fmt.Errorf("connect mongo database: %w", err))
+	}
+	return nil
+}
+
+// getMongoClientOptions translates the options into driver options. The
+// tuning options override the ones of the uri.
+func getMongoClientOptions(_opts *ClientOptions) (mongoOpts *options.ClientOptions, err error) {
+	mongoOpts = &options.ClientOptions{
 		AppName: &AppName,
 	}
 
@@ -127,21 +350,126 @@ func newMongoClient(ctx context.Context, _opts *ClientOptions) (c *mongo.Client,
 		}
 	}
 
-	// attempt to connect with retry
-	bp := backoff.NewExponentialBackOff()
-	err = backoff.Retry(func() error {
-		errMsg := fmt.Sprintf("waiting for connect mongo database, after %f seconds try again.", bp.NextBackOff().Seconds())
-		c, err = mongo.NewClient(mongoOpts)
+	// tls
+	if _opts.Tls || _opts.TlsCaFile != "" || _opts.TlsCertFile != "" {
+		tlsConfig, err := getTlsConfig(_opts)
 		if err != nil {
-			log.WithError(err).Warnf(errMsg)
-			return err
+			return nil, err
 		}
-		if err := c.Connect(ctx); err != nil {
-			log.WithError(err).Warnf(errMsg)
-			return err
+		mongoOpts.SetTLSConfig(tlsConfig)
+	}
+
+	// topology
+	if _opts.ReplicaSet != "" {
+		mongoOpts.SetReplicaSet(_opts.ReplicaSet)
+	}
+	if _opts.DirectConnection != nil {
+		mongoOpts.SetDirect(*_opts.DirectConnection)
+	}
+
+	// read and write concerns
+	if _opts.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(_opts.ReadPreference)
+		if err != nil {
+			return nil, trace.TraceError(err)
 		}
-		return nil
-	}, bp)
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		mongoOpts.SetReadPreference(rp)
+	}
+	if _opts.ReadConcern != "" {
+		mongoOpts.SetReadConcern(readconcern.New(readconcern.Level(_opts.ReadConcern)))
+	}
+	if _opts.WriteConcern != "" {
+		mongoOpts.SetWriteConcern(getWriteConcern(_opts.WriteConcern))
+	}
 
-	return c, nil
+	// timeouts
+	if _opts.ConnectTimeout > 0 {
+		mongoOpts.SetConnectTimeout(_opts.ConnectTimeout)
+	}
+	if _opts.ServerSelectionTimeout > 0 {
+		mongoOpts.SetServerSelectionTimeout(_opts.ServerSelectionTimeout)
+	}
+	if _opts.SocketTimeout > 0 {
+		mongoOpts.SetSocketTimeout(_opts.SocketTimeout)
+	}
+
+	// pool
+	if _opts.MinPoolSize > 0 {
+		mongoOpts.SetMinPoolSize(_opts.MinPoolSize)
+	}
+	if _opts.MaxPoolSize > 0 {
+		mongoOpts.SetMaxPoolSize(_opts.MaxPoolSize)
+	}
+	if _opts.MinPoolSize > 0 && _opts.MaxPoolSize > 0 && _opts.MinPoolSize > _opts.MaxPoolSize {
+		return nil, trace.TraceError(errors.ErrInvalidOptions)
+	}
+
+	// compressors
+	if len(_opts.Compressors) > 0 {
+		for _, compressor := range _opts.Compressors {
+			switch compressor {
+			case "snappy", "zstd", "zlib":
+			default:
+				return nil, trace.TraceError(fmt.Errorf("%w: compressor %s", errors.ErrInvalidOptions, compressor))
+			}
+		}
+		mongoOpts.SetCompressors(_opts.Compressors)
+	}
+
+	// retries
+	if _opts.RetryReads != nil {
+		mongoOpts.SetRetryReads(*_opts.RetryReads)
+	}
+	if _opts.RetryWrites != nil {
+		mongoOpts.SetRetryWrites(*_opts.RetryWrites)
+	}
+
+	if err := mongoOpts.Validate(); err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	return mongoOpts, nil
+}
+
+func getTlsConfig(_opts *ClientOptions) (tlsConfig *tls.Config, err error) {
+	tlsConfig = &tls.Config{
+		InsecureSkipVerify: _opts.TlsInsecure,
+	}
+	if _opts.TlsCaFile != "" {
+		data, err := os.ReadFile(_opts.TlsCaFile)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, trace.TraceError(fmt.Errorf("%w: no certificate in %s", errors.ErrInvalidOptions, _opts.TlsCaFile))
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if _opts.TlsCertFile != "" {
+		keyFile := _opts.TlsKeyFile
+		if keyFile == "" {
+			keyFile = _opts.TlsCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(_opts.TlsCertFile, keyFile)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func getWriteConcern(value string) (wc *writeconcern.WriteConcern) {
+	if value == "majority" {
+		return writeconcern.New(writeconcern.WMajority())
+	}
+	if w, err := strconv.Atoi(value); err == nil {
+		return writeconcern.New(writeconcern.W(w))
+	}
+	return writeconcern.New(writeconcern.WTagSet(value))
 }
@@ -1,11 +1,15 @@
 package mongo
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ClientOption func(options *ClientOptions)
 
 type ClientOptions struct {
-	Context                 context.Context
+	Context                 context.Context `json:"-"`
+	Name                    string
 	Uri                     string
 	Host                    string
 	Port                    string
@@ -16,6 +20,51 @@ type ClientOptions struct {
 	AuthSource              string
 	AuthMechanism           string
 	AuthMechanismProperties map[string]string
+
+	// TLS is enabled if Tls is set or any of the files is given.
+	Tls         bool
+	TlsCaFile   string
+	TlsCertFile string
+	TlsKeyFile  string
+	TlsInsecure bool
+
+	ReplicaSet string
+
+	// ReadPreference is a read preference mode, e.g. "secondaryPreferred".
+	ReadPreference string
+
+	// ReadConcern is a read concern level, e.g. "majority".
+	ReadConcern string
+
+	// WriteConcern is "majority", a number of nodes or a tag set name.
+	WriteConcern string
+
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	SocketTimeout          time.Duration
+	MinPoolSize            uint64
+	MaxPoolSize            uint64
+
+	// Compressors are "snappy", "zstd" or "zlib", in order of preference.
+	Compressors []string
+
+	// RetryReads, RetryWrites and DirectConnection keep the driver defaults
+	// if nil.
+	RetryReads       *bool
+	RetryWrites      *bool
+	DirectConnection *bool
+
+	// ConnectRetryMaxElapsedTime and ConnectRetryMaxInterval bound the
+	// exponential backoff of connecting, and ConnectRetryMaxAttempts the
+	// number of attempts if positive.
+	ConnectRetryMaxElapsedTime time.Duration
+	ConnectRetryMaxInterval    time.Duration
+	ConnectRetryMaxAttempts    int
+
+	// Ping checks the connection when the client is created, retrying with
+	// the connect retry options, so that an unreachable server fails the
+	// creation. Otherwise the client connects on first use.
+	Ping bool
 }
 
 func WithContext(ctx context.Context) ClientOption {
@@ -24,6 +73,14 @@ func WithContext(ctx context.Context) ClientOption {
 	}
 }
 
+// WithClientName caches the client under name, so that it can be retrieved
+// with WithClientName only, and disconnected with DisconnectMongoClient.
+func WithClientName(name string) ClientOption {
+	return func(options *ClientOptions) {
+		options.Name = name
+	}
+}
+
 func WithUri(value string) ClientOption {
 	return func(options *ClientOptions) {
 		options.Uri = value
@@ -77,3 +134,135 @@ func WithAuthMechanism(value string) ClientOption {
 		options.AuthMechanism = value
 	}
 }
+
+func WithTls(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.Tls = value
+	}
+}
+
+// WithTlsCaFile verifies the server certificates with the CA certificates
+// of the PEM file instead of the system ones.
+func WithTlsCaFile(value string) ClientOption {
+	return func(options *ClientOptions) {
+		options.TlsCaFile = value
+	}
+}
+
+// WithTlsCertKeyFile authenticates the client with the certificate and the
+// private key of the PEM files, which may be the same file.
+func WithTlsCertKeyFile(certFile, keyFile string) ClientOption {
+	return func(options *ClientOptions) {
+		options.TlsCertFile = certFile
+		options.TlsKeyFile = keyFile
+	}
+}
+
+// WithTlsInsecure skips the verification of the server certificates.
+func WithTlsInsecure(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.TlsInsecure = value
+	}
+}
+
+func WithReplicaSet(value string) ClientOption {
+	return func(options *ClientOptions) {
+		options.ReplicaSet = value
+	}
+}
+
+func WithReadPreference(value string) ClientOption {
+	return func(options *ClientOptions) {
+		options.ReadPreference = value
+	}
+}
+
+func WithReadConcern(value string) ClientOption {
+	return func(options *ClientOptions) {
+		options.ReadConcern = value
+	}
+}
+
+func WithWriteConcern(value string) ClientOption {
+	return func(options *ClientOptions) {
+		options.WriteConcern = value
+	}
+}
+
+func WithConnectTimeout(value time.Duration) ClientOption {
+	return func(options *ClientOptions) {
+		options.ConnectTimeout = value
+	}
+}
+
+func WithServerSelectionTimeout(value time.Duration) ClientOption {
+	return func(options *ClientOptions) {
+		options.ServerSelectionTimeout = value
+	}
+}
+
+func WithSocketTimeout(value time.Duration) ClientOption {
+	return func(options *ClientOptions) {
+		options.SocketTimeout = value
+	}
+}
+
+func WithMinPoolSize(value uint64) ClientOption {
+	return func(options *ClientOptions) {
+		options.MinPoolSize = value
+	}
+}
+
+func WithMaxPoolSize(value uint64) ClientOption {
+	return func(options *ClientOptions) {
+		options.MaxPoolSize = value
+	}
+}
+
+func WithCompressors(value []string) ClientOption {
+	return func(options *ClientOptions) {
+		options.Compressors = value
+	}
+}
+
+func WithRetryReads(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.RetryReads = &value
+	}
+}
+
+func WithRetryWrites(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.RetryWrites = &value
+	}
+}
+
+func WithDirectConnection(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.DirectConnection = &value
+	}
+}
+
+func WithConnectRetryMaxElapsedTime(value time.Duration) ClientOption {
+	return func(options *ClientOptions) {
+		options.ConnectRetryMaxElapsedTime = value
+	}
+}
+
+func WithConnectRetryMaxInterval(value time.Duration) ClientOption {
+	return func(options *ClientOptions) {
+		options.ConnectRetryMaxInterval = value
+	}
+}
+
+func WithConnectRetryMaxAttempts(value int) ClientOption {
+	return func(options *ClientOptions) {
+		options.ConnectRetryMaxAttempts = value
+	}
+}
+
+func WithPing(value bool) ClientOption {
+	return func(options *ClientOptions) {
+		options.Ping = value
+	}
+}
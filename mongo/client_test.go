@@ -1,8 +1,18 @@
 package mongo
 
 import (
+	"context"
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func setupMongoTest() (err error) {
@@ -21,3 +31,172 @@ func TestMongoInitMongo(t *testing.T) {
 
 	cleanupMongoTest()
 }
+
+func TestGetMongoClient_Concurrent(t *testing.T) {
+	defer func() {
+		_ = CloseAllMongoClients(context.Background())
+	}()
+
+	// clients connect lazily, so that this runs without a server
+	n := 20
+	clients := make([]*mongo.Client, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				clients[i], errs[i] = GetMongoClient(WithClientName("test"), WithHost("test-host"))
+			} else {
+				clients[i], errs[i] = GetMongoClient(WithHost("test-host"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		require.Nil(t, err)
+	}
+	for i := 2; i < n; i++ {
+		require.Same(t, clients[i%2], clients[i])
+	}
+	require.NotSame(t, clients[0], clients[1])
+
+	// named client without the other options
+	c, err := GetMongoClient(WithClientName("test"))
+	require.Nil(t, err)
+	require.Same(t, clients[0], c)
+}
+
+func TestDisconnectMongoClient(t *testing.T) {
+	c1, err := GetMongoClient(WithClientName("test"), WithHost("test-host"))
+	require.Nil(t, err)
+
+	err = DisconnectMongoClient(context.Background(), WithClientName("test"))
+	require.Nil(t, err)
+
+	// disconnecting an unknown client is a no-op
+	err = DisconnectMongoClient(context.Background(), WithClientName("test"))
+	require.Nil(t, err)
+
+	// a new client is created after disconnecting
+	c2, err := GetMongoClient(WithClientName("test"), WithHost("test-host"))
+	require.Nil(t, err)
+	require.NotSame(t, c1, c2)
+
+	err = CloseAllMongoClients(context.Background())
+	require.Nil(t, err)
+	_mu.Lock()
+	require.Len(t, _clientMap, 0)
+	_mu.Unlock()
+}
+
+func TestGetClientKey(t *testing.T) {
+	key, err := getClientKey(getClientOptions(WithUsername("user"), WithPassword("secret")))
+	require.Nil(t, err)
+	require.False(t, strings.Contains(key, "secret"))
+	require.False(t, strings.Contains(key, "user"))
+
+	key2, err := getClientKey(getClientOptions(WithUsername("user"), WithPassword("other")))
+	require.Nil(t, err)
+	require.NotEqual(t, key, key2)
+
+	key, err = getClientKey(getClientOptions(WithClientName("test"), WithPassword("secret")))
+	require.Nil(t, err)
+	require.Equal(t, "name:test", key)
+}
+
+func TestGetMongoClient_ConnectFailure(t *testing.T) {
+	defer func() {
+		_ = CloseAllMongoClients(context.Background())
+	}()
+
+	start := time.Now()
+	_, err := GetMongoClient(
+		WithHost("127.0.0.1"),
+		WithPort("1"),
+		WithServerSelectionTimeout(100*time.Millisecond),
+		WithConnectRetryMaxAttempts(2),
+		WithPing(true),
+	)
+	require.NotNil(t, err)
+	require.True(t, time.Since(start) < 10*time.Second)
+
+	// without ping, the client connects on first use and does not block
+	start = time.Now()
+	_, err = GetMongoClient(WithHost("127.0.0.1"), WithPort("1"))
+	require.Nil(t, err)
+	require.True(t, time.Since(start) < time.Second)
+
+	// invalid options fail without retrying
+	start = time.Now()
+	_, err = GetMongoClient(WithUri("invalid://uri"))
+	require.NotNil(t, err)
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func TestGetMongoClientOptions(t *testing.T) {
+	mongoOpts, err := getMongoClientOptions(getClientOptions(
+		WithHost("test-host"),
+		WithReplicaSet("rs0"),
+		WithReadPreference("secondaryPreferred"),
+		WithReadConcern("majority"),
+		WithWriteConcern("majority"),
+		WithConnectTimeout(5*time.Second),
+		WithServerSelectionTimeout(10*time.Second),
+		WithSocketTimeout(30*time.Second),
+		WithMinPoolSize(2),
+		WithMaxPoolSize(20),
+		WithCompressors([]string{"zstd", "snappy"}),
+		WithRetryReads(false),
+		WithRetryWrites(false),
+		WithTls(true),
+		WithTlsInsecure(true),
+	))
+	require.Nil(t, err)
+	require.Equal(t, "rs0", *mongoOpts.ReplicaSet)
+	require.Equal(t, readpref.SecondaryPreferredMode, mongoOpts.ReadPreference.Mode())
+	require.Equal(t, "majority", mongoOpts.ReadConcern.GetLevel())
+	require.True(t, mongoOpts.WriteConcern.Acknowledged())
+	require.Equal(t, 5*time.Second, *mongoOpts.ConnectTimeout)
+	require.Equal(t, 10*time.Second, *mongoOpts.ServerSelectionTimeout)
+	require.Equal(t, 30*time.Second, *mongoOpts.SocketTimeout)
+	require.Equal(t, uint64(2), *mongoOpts.MinPoolSize)
+	require.Equal(t, uint64(20), *mongoOpts.MaxPoolSize)
+	require.Equal(t, []string{"zstd", "snappy"}, mongoOpts.Compressors)
+	require.False(t, *mongoOpts.RetryReads)
+	require.False(t, *mongoOpts.RetryWrites)
+	require.True(t, mongoOpts.TLSConfig.InsecureSkipVerify)
+
+	// viper
+	viper.Set("mongo.replicaSet", "rs1")
+	viper.Set("mongo.retryWrites", true)
+	defer viper.Set("mongo.replicaSet", nil)
+	defer viper.Set("mongo.retryWrites", nil)
+	mongoOpts, err = getMongoClientOptions(getClientOptions())
+	require.Nil(t, err)
+	require.Equal(t, "rs1", *mongoOpts.ReplicaSet)
+	require.True(t, *mongoOpts.RetryWrites)
+	require.Nil(t, mongoOpts.RetryReads)
+}
+
+func TestGetMongoClientOptions_Invalid(t *testing.T) {
+	_, err := getMongoClientOptions(getClientOptions(WithReadPreference("invalid")))
+	require.NotNil(t, err)
+
+	_, err = getMongoClientOptions(getClientOptions(WithCompressors([]string{"gzip"})))
+	require.True(t, goerrors.Is(err, errors.ErrInvalidOptions))
+
+	_, err = getMongoClientOptions(getClientOptions(WithMinPoolSize(10), WithMaxPoolSize(5)))
+	require.True(t, goerrors.Is(err, errors.ErrInvalidOptions))
+
+	_, err = getMongoClientOptions(getClientOptions(WithTlsCaFile("not-exists.pem")))
+	require.NotNil(t, err)
+}
+
+func TestGetWriteConcern(t *testing.T) {
+	require.Equal(t, writeconcern.New(writeconcern.WMajority()), getWriteConcern("majority"))
+	require.Equal(t, writeconcern.New(writeconcern.W(2)), getWriteConcern("2"))
+	require.Equal(t, writeconcern.New(writeconcern.WTagSet("dc")), getWriteConcern("dc"))
+}
@@ -2,12 +2,14 @@ package mongo
 
 import (
 	"context"
+	"fmt"
 	"github.com/crawlab-team/crawlab-db/errors"
 	"github.com/crawlab-team/go-trace"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 type ColInterface interface {
@@ -16,16 +18,36 @@ type ColInterface interface {
 	UpdateId(id primitive.ObjectID, update interface{}) (err error)
 	Update(query bson.M, update interface{}) (err error)
 	UpdateWithOptions(query bson.M, update interface{}, opts *options.UpdateOptions) (err error)
+	UpdateIdWithVersion(id primitive.ObjectID, version int64, update interface{}) (err error)
 	ReplaceId(id primitive.ObjectID, doc interface{}) (err error)
 	Replace(query bson.M, doc interface{}) (err error)
 	ReplaceWithOptions(query bson.M, doc interface{}, opts *options.ReplaceOptions) (err error)
+	ReplaceIdWithVersion(id primitive.ObjectID, version int64, doc interface{}) (err error)
 	DeleteId(id primitive.ObjectID) (err error)
 	Delete(query bson.M) (err error)
 	DeleteWithOptions(query bson.M, opts *options.DeleteOptions) (err error)
+	UpdateIdWithResult(id primitive.ObjectID, update interface{}, opts ...WriteOption) (res *WriteResult, err error)
+	UpdateWithResult(query bson.M, update interface{}, updateOpts *options.UpdateOptions, opts ...WriteOption) (res *WriteResult, err error)
+	ReplaceIdWithResult(id primitive.ObjectID, doc interface{}, opts ...WriteOption) (res *WriteResult, err error)
+	ReplaceWithResult(query bson.M, doc interface{}, replaceOpts *options.ReplaceOptions, opts ...WriteOption) (res *WriteResult, err error)
+	DeleteIdWithResult(id primitive.ObjectID, opts ...WriteOption) (res *WriteResult, err error)
+	DeleteWithResult(query bson.M, deleteOpts *options.DeleteOptions, opts ...WriteOption) (res *WriteResult, err error)
+	RestoreId(id primitive.ObjectID) (err error)
+	Restore(query bson.M) (err error)
+	PurgeId(id primitive.ObjectID) (err error)
+	Purge(query bson.M) (err error)
+	BulkWrite() (bw *BulkWrite)
 	Find(query bson.M, opts *FindOptions) (fr *FindResult)
 	FindId(id primitive.ObjectID) (fr *FindResult)
+	FindIdWithOptions(id primitive.ObjectID, opts *FindOptions) (fr *FindResult)
+	FindPage(query bson.M, opts *FindPageOptions, results interface{}) (total int, err error)
+	FindOneAndUpdate(query bson.M, update interface{}, opts *FindAndModifyOptions) (fr *FindResult)
+	FindOneAndReplace(query bson.M, doc interface{}, opts *FindAndModifyOptions) (fr *FindResult)
+	FindOneAndDelete(query bson.M, opts *FindAndModifyOptions) (fr *FindResult)
+	Distinct(fieldName string, query bson.M) (values []interface{}, err error)
 	Count(query bson.M) (total int, err error)
 	Aggregate(pipeline mongo.Pipeline, opts *options.AggregateOptions) (fr *FindResult)
+	Watch(pipeline mongo.Pipeline, opts *WatchOptions, handler func(evt *ChangeEvent) (err error)) (err error)
 	CreateIndex(indexModel mongo.IndexModel) (err error)
 	CreateIndexes(indexModels []mongo.IndexModel) (err error)
 	MustCreateIndex(indexModel mongo.IndexModel)
@@ -33,24 +55,108 @@ type ColInterface interface {
 	DeleteIndex(name string) (err error)
 	DeleteAllIndexes() (err error)
 	ListIndexes() (indexes []map[string]interface{}, err error)
+	WithContext(ctx context.Context) (c *Col)
 	GetContext() (ctx context.Context)
 	GetName() (name string)
 	GetCollection() (c *mongo.Collection)
 }
 
 type FindOptions struct {
-	Skip  int
-	Limit int
-	Sort  bson.D
+	Skip            int
+	Limit           int
+	Sort            bson.D
+	BatchSize       int
+	Projection      interface{}
+	Hint            interface{}
+	Collation       *options.Collation
+	MaxTime         time.Duration
+	AllowDiskUse    bool
+	NoCursorTimeout bool
 }
 
+func (opts *FindOptions) toFindOptions() (_opts *options.FindOptions) {
+	_opts = &options.FindOptions{}
+	if opts == nil {
+		return _opts
+	}
+	if opts.Skip != 0 {
+		_opts.SetSkip(int64(opts.Skip))
+	}
+	if opts.Limit != 0 {
+		_opts.SetLimit(int64(opts.Limit))
+	}
+	if opts.Sort != nil {
+		_opts.SetSort(opts.Sort)
+	}
+	if opts.BatchSize != 0 {
+		_opts.SetBatchSize(int32(opts.BatchSize))
+	}
+	if opts.Projection != nil {
+		_opts.SetProjection(opts.Projection)
+	}
+	if opts.Hint != nil {
+		_opts.SetHint(opts.Hint)
+	}
+	if opts.Collation != nil {
+		_opts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		_opts.SetMaxTime(opts.MaxTime)
+	}
+	if opts.AllowDiskUse {
+		_opts.SetAllowDiskUse(true)
+	}
+	if opts.NoCursorTimeout {
+		_opts.SetNoCursorTimeout(true)
+	}
+	return _opts
+}
+
+// toFindOneOptions converts the options that apply to a single document.
+func (opts *FindOptions) toFindOneOptions() (_opts *options.FindOneOptions) {
+	_opts = &options.FindOneOptions{}
+	if opts == nil {
+		return _opts
+	}
+	if opts.Skip != 0 {
+		_opts.SetSkip(int64(opts.Skip))
+	}
+	if opts.Sort != nil {
+		_opts.SetSort(opts.Sort)
+	}
+	if opts.Projection != nil {
+		_opts.SetProjection(opts.Projection)
+	}
+	if opts.Hint != nil {
+		_opts.SetHint(opts.Hint)
+	}
+	if opts.Collation != nil {
+		_opts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		_opts.SetMaxTime(opts.MaxTime)
+	}
+	return _opts
+}
+
+// SoftDeleteField is set to the deletion time of soft deleted documents.
+const SoftDeleteField = "deleted_ts"
+
 type Col struct {
 	ctx context.Context
 	db  *mongo.Database
 	c   *mongo.Collection
+
+	softDelete bool
+	audit      bool
+	versioned  bool
 }
 
 func (col *Col) Insert(doc interface{}) (id primitive.ObjectID, err error) {
+	doc, err = col.getInsertDoc(doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
 	res, err := col.c.InsertOne(col.ctx, doc)
 	if err != nil {
 		return primitive.NilObjectID, trace.TraceError(err)
@@ -62,7 +168,14 @@ func (col *Col) Insert(doc interface{}) (id primitive.ObjectID, err error) {
 }
 
 func (col *Col) InsertMany(docs []interface{}) (ids []primitive.ObjectID, err error) {
-	res, err := col.c.InsertMany(col.ctx, docs)
+	_docs := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		_docs[i], err = col.getInsertDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := col.c.InsertMany(col.ctx, _docs)
 	if err != nil {
 		return nil, trace.TraceError(err)
 	}
@@ -79,11 +192,8 @@ func (col *Col) InsertMany(docs []interface{}) (ids []primitive.ObjectID, err er
 }
 
 func (col *Col) UpdateId(id primitive.ObjectID, update interface{}) (err error) {
-	_, err = col.c.UpdateOne(col.ctx, bson.M{"_id": id}, update)
-	if err != nil {
-		return trace.TraceError(err)
-	}
-	return nil
+	_, err = col.UpdateIdWithResult(id, update)
+	return err
 }
 
 func (col *Col) Update(query bson.M, update interface{}) (err error) {
@@ -91,15 +201,8 @@ func (col *Col) Update(query bson.M, update interface{}) (err error) {
 }
 
 func (col *Col) UpdateWithOptions(query bson.M, update interface{}, opts *options.UpdateOptions) (err error) {
-	if opts == nil {
-		_, err = col.c.UpdateMany(col.ctx, query, update)
-	} else {
-		_, err = col.c.UpdateMany(col.ctx, query, update, opts)
-	}
-	if err != nil {
-		return trace.TraceError(err)
-	}
-	return nil
+	_, err = col.UpdateWithResult(query, update, opts)
+	return err
 }
 
 func (col *Col) ReplaceId(id primitive.ObjectID, doc interface{}) (err error) {
@@ -111,23 +214,13 @@ func (col *Col) Replace(query bson.M, doc interface{}) (err error) {
 }
 
 func (col *Col) ReplaceWithOptions(query bson.M, doc interface{}, opts *options.ReplaceOptions) (err error) {
-	if opts == nil {
-		_, err = col.c.ReplaceOne(col.ctx, query, doc)
-	} else {
-		_, err = col.c.ReplaceOne(col.ctx, query, doc, opts)
-	}
-	if err != nil {
-		return trace.TraceError(err)
-	}
-	return nil
+	_, err = col.ReplaceWithResult(query, doc, opts)
+	return err
 }
 
 func (col *Col) DeleteId(id primitive.ObjectID) (err error) {
-	_, err = col.c.DeleteOne(col.ctx, bson.M{"_id": id})
-	if err != nil {
-		return trace.TraceError(err)
-	}
-	return nil
+	_, err = col.DeleteIdWithResult(id)
+	return err
 }
 
 func (col *Col) Delete(query bson.M) (err error) {
@@ -135,33 +228,48 @@ func (col *Col) Delete(query bson.M) (err error) {
 }
 
 func (col *Col) DeleteWithOptions(query bson.M, opts *options.DeleteOptions) (err error) {
-	if opts == nil {
-		_, err = col.c.DeleteMany(col.ctx, query)
-	} else {
-		_, err = col.c.DeleteMany(col.ctx, query, opts)
+	_, err = col.DeleteWithResult(query, opts)
+	return err
+}
+
+func (col *Col) RestoreId(id primitive.ObjectID) (err error) {
+	return col.Restore(bson.M{"_id": id})
+}
+
+// Restore undoes the soft deletion of the documents matching query.
+func (col *Col) Restore(query bson.M) (err error) {
+	_, err = col.c.UpdateMany(col.ctx, getDeletedQuery(query), bson.M{
+		"$unset": bson.M{SoftDeleteField: ""},
+	})
+	if err != nil {
+		return trace.TraceError(err)
 	}
+	return nil
+}
+
+func (col *Col) PurgeId(id primitive.ObjectID) (err error) {
+	return col.Purge(bson.M{"_id": id})
+}
+
+// Purge permanently removes the soft deleted documents matching query, e.g.
+// bson.M{SoftDeleteField: bson.M{"$lt": ts}} for those deleted before ts.
+func (col *Col) Purge(query bson.M) (err error) {
+	_, err = col.c.DeleteMany(col.ctx, getDeletedQuery(query))
 	if err != nil {
 		return trace.TraceError(err)
 	}
 	return nil
 }
 
-func (col *Col) Find(query bson.M, opts *FindOptions) (fr *FindResult) {
-	_opts := &options.FindOptions{}
-	if opts != nil {
-		if opts.Skip != 0 {
-			skipInt64 := int64(opts.Skip)
-			_opts.Skip = &skipInt64
-		}
-		if opts.Limit != 0 {
-			limitInt64 := int64(opts.Limit)
-			_opts.Limit = &limitInt64
-		}
-		if opts.Sort != nil {
-			_opts.Sort = opts.Sort
-		}
+func (col *Col) BulkWrite() (bw *BulkWrite) {
+	return &BulkWrite{
+		col:     col,
+		ordered: true,
 	}
-	cur, err := col.c.Find(col.ctx, query, _opts)
+}
+
+func (col *Col) Find(query bson.M, opts *FindOptions) (fr *FindResult) {
+	cur, err := col.c.Find(col.ctx, col.getQuery(query), opts.toFindOptions())
 	if err != nil {
 		return &FindResult{
 			col: col,
@@ -176,7 +284,11 @@ func (col *Col) Find(query bson.M, opts *FindOptions) (fr *FindResult) {
 }
 
 func (col *Col) FindId(id primitive.ObjectID) (fr *FindResult) {
-	res := col.c.FindOne(col.ctx, bson.M{"_id": id})
+	return col.FindIdWithOptions(id, nil)
+}
+
+func (col *Col) FindIdWithOptions(id primitive.ObjectID, opts *FindOptions) (fr *FindResult) {
+	res := col.c.FindOne(col.ctx, col.getQuery(bson.M{"_id": id}), opts.toFindOneOptions())
 	if res.Err() != nil {
 		return &FindResult{
 			col: col,
@@ -191,7 +303,7 @@ func (col *Col) FindId(id primitive.ObjectID) (fr *FindResult) {
 }
 
 func (col *Col) Count(query bson.M) (total int, err error) {
-	totalInt64, err := col.c.CountDocuments(col.ctx, query)
+	totalInt64, err := col.c.CountDocuments(col.ctx, col.getQuery(query))
 	if err != nil {
 		return 0, err
 	}
@@ -200,7 +312,7 @@ func (col *Col) Count(query bson.M) (total int, err error) {
 }
 
 func (col *Col) Aggregate(pipeline mongo.Pipeline, opts *options.AggregateOptions) (fr *FindResult) {
-	cur, err := col.c.Aggregate(col.ctx, pipeline, opts)
+	cur, err := col.c.Aggregate(col.ctx, col.getPipeline(pipeline), opts)
 	if err != nil {
 		return &FindResult{
 			col: col,
@@ -271,6 +383,75 @@ func (col *Col) ListIndexes() (indexes []map[string]interface{}, err error) {
 	return indexes, nil
 }
 
+// WithContext returns a copy of the collection bound to ctx, so that its
+// operations are canceled with ctx. Pass a mongo.SessionContext to run the
+// operations in a session.
+func (col *Col) WithContext(ctx context.Context) (c *Col) {
+	_col := *col
+	_col.ctx = ctx
+	return &_col
+}
+
+// getQuery adds the filters implied by the options of the collection to
+// query, without modifying it.
+func (col *Col) getQuery(query bson.M) (q bson.M) {
+	if !col.softDelete {
+		if query == nil {
+			return bson.M{}
+		}
+		return query
+	}
+	q = bson.M{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if _, ok := q[SoftDeleteField]; !ok {
+		q[SoftDeleteField] = bson.M{"$exists": false}
+	}
+	return q
+}
+
+func (col *Col) getSoftDeleteUpdate() (update bson.M) {
+	return bson.M{
+		"$set": bson.M{SoftDeleteField: time.Now()},
+	}
+}
+
+// getPipeline adds the filters implied by the options of the collection to
+// pipeline, without modifying it. The filters follow the stages that must be
+// first, and are skipped for those not outputting the documents of the
+// collection.
+func (col *Col) getPipeline(pipeline mongo.Pipeline) (p mongo.Pipeline) {
+	if !col.softDelete {
+		return pipeline
+	}
+	match := bson.D{{Key: "$match", Value: col.getQuery(nil)}}
+	if len(pipeline) > 0 && len(pipeline[0]) > 0 {
+		switch pipeline[0][0].Key {
+		case "$geoNear", "$search", "$searchMeta", "$vectorSearch":
+			p = mongo.Pipeline{pipeline[0], match}
+			return append(p, pipeline[1:]...)
+		case "$collStats", "$indexStats", "$planCacheStats", "$currentOp",
+			"$listSessions", "$listLocalSessions", "$listSearchIndexes",
+			"$changeStream", "$documents":
+			return pipeline
+		}
+	}
+	return append(mongo.Pipeline{match}, pipeline...)
+}
+
+// getDeletedQuery restricts query to soft deleted documents.
+func getDeletedQuery(query bson.M) (q bson.M) {
+	q = bson.M{}
+	for k, v := range query {
+		q[k] = v
+	}
+	if _, ok := q[SoftDeleteField]; !ok {
+		q[SoftDeleteField] = bson.M{"$exists": true}
+	}
+	return q
+}
+
 func (col *Col) GetContext() (ctx context.Context) {
 	return col.ctx
 }
@@ -283,20 +464,43 @@ func (col *Col) GetCollection() (c *mongo.Collection) {
 	return col.c
 }
 
-func GetMongoCol(colName string) (col *Col) {
-	return GetMongoColWithDb(colName, nil)
+func GetMongoCol(colName string, opts ...ColOption) (col *Col) {
+	return GetMongoColWithDb(colName, nil, opts...)
 }
 
-func GetMongoColWithDb(colName string, db *mongo.Database) (col *Col) {
-	ctx := context.Background()
+// GetMongoColWithDb returns the collection of db, or of the default database
+// if db is nil. It panics if the default client cannot be created, e.g. with
+// invalid options; use GetMongoColWithError to handle such failures.
+func GetMongoColWithDb(colName string, db *mongo.Database, opts ...ColOption) (col *Col) {
 	if db == nil {
 		db = GetMongoDb("")
 	}
+	if db == nil {
+		panic(fmt.Sprintf("get mongo collection %s: no database, see GetMongoColWithError", colName))
+	}
+	return newCol(colName, db, opts...)
+}
+
+// GetMongoColWithError is like GetMongoCol but returns the error of
+// connecting to the default database instead of panicking.
+func GetMongoColWithError(colName string, opts ...ColOption) (col *Col, err error) {
+	db, err := GetMongoDbWithError("")
+	if err != nil {
+		return nil, err
+	}
+	return newCol(colName, db, opts...), nil
+}
+
+func newCol(colName string, db *mongo.Database, opts ...ColOption) (col *Col) {
+	ctx := context.Background()
 	c := db.Collection(colName)
 	col = &Col{
 		ctx: ctx,
 		db:  db,
 		c:   c,
 	}
+	for _, op := range opts {
+		op(col)
+	}
 	return col
 }
@@ -0,0 +1,40 @@
+package mongo
+
+type ColOption func(col *Col)
+
+// WithColSoftDelete makes Delete and DeleteId set the SoftDeleteField of the
+// documents instead of removing them, and the finds, counts, aggregations,
+// updates and replacements skip such documents unless the query refers to
+// SoftDeleteField. Aggregations skip them with a $match stage, which follows
+// a leading $geoNear or $search stage, and is omitted if the leading stage
+// does not output the documents of the collection, e.g. $collStats or
+// $indexStats. Use Restore and Purge to undo or complete the deletion. The
+// deletes of bulk writes are soft too, but their other operations and raw
+// models are sent as is.
+func WithColSoftDelete() ColOption {
+	return func(col *Col) {
+		col.softDelete = true
+	}
+}
+
+// WithColAudit makes Insert and InsertMany stamp CreatedTsField and
+// CreatedByField, and the updates and replacements stamp UpdatedTsField and
+// UpdatedByField, with the current time and the user of the context set by
+// ContextWithUser. Bulk writes are not affected.
+func WithColAudit() ColOption {
+	return func(col *Col) {
+		col.audit = true
+	}
+}
+
+// WithColVersion enables optimistic locking with VersionField. Inserted
+// documents start at version 1 and updates increment the version. Replace,
+// ReplaceId and ReplaceWithOptions only replace the document at the version
+// of the replacement and return errors.ErrorMongoVersionConflict otherwise.
+// Use UpdateIdWithVersion for updates that check the version. Bulk writes
+// are not affected.
+func WithColVersion() ColOption {
+	return func(col *Col) {
+		col.versioned = true
+	}
+}
@@ -1,14 +1,17 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"strconv"
 	"testing"
+	"time"
 )
 
 type ColTestObject struct {
@@ -48,6 +51,17 @@ func cleanupColTest(to *ColTestObject) {
 	_ = to.col.db.Drop(to.col.ctx)
 }
 
+func TestGetMongoCol_InvalidOptions(t *testing.T) {
+	viper.Set("mongo.uri", "invalid://uri")
+	defer viper.Set("mongo.uri", nil)
+
+	require.PanicsWithValue(t, "get mongo collection test_col: no database, see GetMongoColWithError", func() {
+		GetMongoCol("test_col")
+	})
+	_, err := GetMongoColWithError("test_col")
+	require.NotNil(t, err)
+}
+
 func TestGetMongoCol(t *testing.T) {
 	colName := "test_col"
 
@@ -59,7 +73,7 @@ func TestGetMongoColWithDb(t *testing.T) {
 	dbName := "test_db"
 	colName := "test_col"
 
-	col := GetMongoColWithDb(colName, dbName)
+	col := GetMongoColWithDb(colName, GetMongoDb(dbName))
 	require.Equal(t, colName, col.c.Name())
 	require.Equal(t, dbName, col.db.Name())
 }
@@ -461,3 +475,168 @@ func TestCol_DeleteIndexes(t *testing.T) {
 
 	cleanupColTest(to)
 }
+
+func TestCol_WithContext(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	_, err = to.col.Insert(bson.M{"key": "value"})
+	require.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	col := to.col.WithContext(ctx)
+	require.Equal(t, ctx, col.GetContext())
+	require.NotEqual(t, ctx, to.col.GetContext())
+
+	cancel()
+	var docs []map[string]string
+	err = col.Find(nil, nil).All(&docs)
+	require.NotNil(t, err)
+
+	_, err = col.Count(nil)
+	require.NotNil(t, err)
+
+	cleanupColTest(to)
+}
+
+func TestCol_Find_Options(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	err = to.col.CreateIndex(mongo.IndexModel{
+		Keys: bson.D{{Key: "key", Value: 1}},
+	})
+	require.Nil(t, err)
+
+	n := 10
+	var docs []interface{}
+	for i := 0; i < n; i++ {
+		docs = append(docs, TestDocument{
+			Key:   fmt.Sprintf("Value-%d", i),
+			Value: i,
+			Tags:  []string{"test tag"},
+		})
+	}
+	ids, err := to.col.InsertMany(docs)
+	require.Nil(t, err)
+
+	var resDocs []TestDocument
+	err = to.col.Find(bson.M{"key": "value-1"}, &FindOptions{
+		Projection:      bson.M{"tags": 0},
+		Collation:       &options.Collation{Locale: "en", Strength: 2},
+		MaxTime:         10 * time.Second,
+		AllowDiskUse:    true,
+		NoCursorTimeout: true,
+	}).All(&resDocs)
+	require.Nil(t, err)
+	require.Len(t, resDocs, 1)
+	require.Equal(t, 1, resDocs[0].Value)
+	require.Empty(t, resDocs[0].Tags)
+
+	var doc TestDocument
+	err = to.col.FindIdWithOptions(ids[0], &FindOptions{
+		Projection: bson.M{"key": 1},
+		Hint:       bson.D{{Key: "key", Value: 1}},
+	}).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "Value-0", doc.Key)
+	require.Empty(t, doc.Tags)
+
+	cleanupColTest(to)
+}
+
+func TestCol_SoftDelete(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColSoftDelete())
+
+	var docs []interface{}
+	for i := 0; i < 5; i++ {
+		docs = append(docs, TestDocument{
+			Key:   "key-" + strconv.Itoa(i),
+			Value: i,
+		})
+	}
+	ids, err := col.InsertMany(docs)
+	require.Nil(t, err)
+
+	// delete
+	err = col.DeleteId(ids[0])
+	require.Nil(t, err)
+	err = col.Delete(bson.M{"value": bson.M{"$gte": 3}})
+	require.Nil(t, err)
+	total, err := col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 2, total)
+	err = col.FindId(ids[0]).One(&TestDocument{})
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	var resDocs []TestDocument
+	err = col.Find(nil, nil).All(&resDocs)
+	require.Nil(t, err)
+	require.Len(t, resDocs, 2)
+	var results []TestAggregateResult
+	err = col.Aggregate(mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": nil, "count": bson.M{"$sum": 1}}}},
+	}, nil).All(&results)
+	require.Nil(t, err)
+	require.Equal(t, 2, results[0].Count)
+
+	// deleted documents are kept
+	total, err = to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 5, total)
+	total, err = col.Count(bson.M{SoftDeleteField: bson.M{"$exists": true}})
+	require.Nil(t, err)
+	require.Equal(t, 3, total)
+
+	// restore
+	err = col.RestoreId(ids[0])
+	require.Nil(t, err)
+	err = col.FindId(ids[0]).One(&TestDocument{})
+	require.Nil(t, err)
+
+	// purge
+	err = col.Purge(bson.M{})
+	require.Nil(t, err)
+	total, err = to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 3, total)
+	total, err = col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 3, total)
+
+	cleanupColTest(to)
+}
+
+func TestCol_getQuery(t *testing.T) {
+	col := &Col{}
+	query := bson.M{"key": "value"}
+	require.Equal(t, query, col.getQuery(query))
+
+	col = &Col{softDelete: true}
+	require.Equal(t, bson.M{
+		"key":           "value",
+		SoftDeleteField: bson.M{"$exists": false},
+	}, col.getQuery(query))
+	require.Equal(t, bson.M{"key": "value"}, query)
+
+	query = bson.M{SoftDeleteField: bson.M{"$lt": time.Now()}}
+	require.Equal(t, query, col.getQuery(query))
+}
+
+func TestCol_getPipeline(t *testing.T) {
+	pipeline := mongo.Pipeline{{{Key: "$group", Value: bson.M{"_id": "$key"}}}}
+	col := &Col{}
+	require.Equal(t, pipeline, col.getPipeline(pipeline))
+
+	col = &Col{softDelete: true}
+	match := bson.D{{Key: "$match", Value: bson.M{SoftDeleteField: bson.M{"$exists": false}}}}
+	require.Equal(t, mongo.Pipeline{match, pipeline[0]}, col.getPipeline(pipeline))
+	require.Equal(t, mongo.Pipeline{match}, col.getPipeline(nil))
+
+	// stages that must be first
+	geoNear := bson.D{{Key: "$geoNear", Value: bson.M{"near": []float64{0, 0}, "distanceField": "dist"}}}
+	require.Equal(t, mongo.Pipeline{geoNear, match, pipeline[0]}, col.getPipeline(mongo.Pipeline{geoNear, pipeline[0]}))
+	collStats := mongo.Pipeline{{{Key: "$collStats", Value: bson.M{"count": bson.M{}}}}}
+	require.Equal(t, collStats, col.getPipeline(collStats))
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/crawlab-team/crawlab-db"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/url"
+	"strings"
+)
+
+func init() {
+	db.RegisterDataSourceDriver(generic.DataSourceTypeMongo, db.DataSourceDriver{
+		Factory:          newMongoDataSource,
+		ConnectionString: getMongoConnectionString,
+		HealthCheck:      checkMongoDataSource,
+	})
+}
+
+func newMongoDataSource(opts *db.DataSourceOptions) (conn interface{}, err error) {
+	return GetMongoClient(
+		WithUri(opts.Uri),
+		WithHost(opts.Host),
+		WithPort(opts.Port),
+		WithHosts(opts.Hosts),
+		WithUsername(opts.Username),
+		WithPassword(opts.Password),
+		WithDb(opts.Database),
+	)
+}
+
+func getMongoConnectionString(opts *db.DataSourceOptions) (connStr string, err error) {
+	if opts.Uri != "" {
+		return opts.Uri, nil
+	}
+	u := url.URL{
+		Scheme: "mongodb",
+		Path:   "/" + opts.Database,
+	}
+	if opts.Username != "" {
+		u.User = url.UserPassword(opts.Username, opts.Password)
+	}
+	if len(opts.Hosts) > 0 {
+		u.Host = strings.Join(opts.Hosts, ",")
+	} else {
+		u.Host = fmt.Sprintf("%s:%s", opts.Host, opts.Port)
+	}
+	return u.String(), nil
+}
+
+func checkMongoDataSource(conn interface{}) (err error) {
+	c, ok := conn.(*mongo.Client)
+	if !ok {
+		return trace.TraceError(errors.ErrInvalidType)
+	}
+	if err := c.Ping(context.Background(), nil); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
@@ -6,7 +6,18 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// GetMongoDb returns the database, or nil if the client cannot be created.
+// Use GetMongoDbWithError to handle such failures.
 func GetMongoDb(dbName string, opts ...DbOption) (db *mongo.Database) {
+	db, err := GetMongoDbWithError(dbName, opts...)
+	if err != nil {
+		trace.PrintError(err)
+		return nil
+	}
+	return db
+}
+
+func GetMongoDbWithError(dbName string, opts ...DbOption) (db *mongo.Database, err error) {
 	if dbName == "" {
 		dbName = viper.GetString("mongo.db")
 	}
@@ -19,17 +30,13 @@ func GetMongoDb(dbName string, opts ...DbOption) (db *mongo.Database) {
 		op(_opts)
 	}
 
-	var c *mongo.Client
-	if _opts.client == nil {
-		var err error
+	c := _opts.client
+	if c == nil {
 		c, err = GetMongoClient()
 		if err != nil {
-			trace.PrintError(err)
-			return nil
+			return nil, err
 		}
-	} else {
-		c = _opts.client
 	}
 
-	return c.Database(dbName, nil)
+	return c.Database(dbName, nil), nil
 }
@@ -0,0 +1,222 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strings"
+)
+
+// getInsertDoc adds the fields implied by the options of the collection to a
+// document to insert.
+func (col *Col) getInsertDoc(doc interface{}) (_doc interface{}, err error) {
+	if !col.audit && !col.versioned {
+		return doc, nil
+	}
+	d, err := toBsonD(doc)
+	if err != nil {
+		return nil, err
+	}
+	if col.audit {
+		d = setBsonFields(d, col.getAuditFields(CreatedTsField, CreatedByField))
+	}
+	if col.versioned {
+		if version, _ := getBsonInt(d, VersionField); version == 0 {
+			d = setBsonFields(d, bson.D{{Key: VersionField, Value: int64(1)}})
+		}
+	}
+	return d, nil
+}
+
+// getReplace adds the fields implied by the options of the collection to a
+// replacement document. In versioned collections, the query also matches the
+// version of the document, which is incremented.
+func (col *Col) getReplace(query bson.M, doc interface{}) (_query bson.M, _doc interface{}, err error) {
+	if !col.audit && !col.versioned {
+		return query, doc, nil
+	}
+	d, err := toBsonD(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if col.audit {
+		d = setBsonFields(d, col.getAuditFields(UpdatedTsField, UpdatedByField))
+	}
+	if col.versioned {
+		version, ok := getBsonInt(d, VersionField)
+		if !ok {
+			return nil, nil, trace.TraceError(errors.ErrMissingValue)
+		}
+		_query = bson.M{}
+		for k, v := range query {
+			_query[k] = v
+		}
+		_query[VersionField] = version
+		query = _query
+		d = setBsonFields(d, bson.D{{Key: VersionField, Value: version + 1}})
+	}
+	return query, d, nil
+}
+
+// getUpdate adds the fields implied by the options of the collection to an
+// update document, or as a $set stage to an update pipeline. Audited upserts
+// also set the created fields on insert.
+func (col *Col) getUpdate(update interface{}, upsert bool) (_update interface{}, err error) {
+	if !col.audit && !col.versioned {
+		return update, nil
+	}
+
+	// pipeline
+	v := reflect.ValueOf(update)
+	if v.Kind() == reflect.Slice && !isBsonDocument(update) {
+		var stages []interface{}
+		for i := 0; i < v.Len(); i++ {
+			stages = append(stages, v.Index(i).Interface())
+		}
+		var set bson.D
+		if col.audit {
+			set = append(set, col.getAuditFields(UpdatedTsField, UpdatedByField)...)
+		}
+		if col.versioned {
+			set = append(set, bson.E{Key: VersionField, Value: bson.M{
+				"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + VersionField, 0}}, 1},
+			}})
+		}
+		return append(stages, bson.D{{Key: "$set", Value: set}}), nil
+	}
+
+	// update document
+	d, err := toBsonD(update)
+	if err != nil {
+		return nil, err
+	}
+	if col.audit {
+		d, err = mergeUpdateOperator(d, "$set", col.getAuditFields(UpdatedTsField, UpdatedByField))
+		if err != nil {
+			return nil, err
+		}
+		if upsert {
+			d, err = mergeUpdateOperator(d, "$setOnInsert", col.getAuditFields(CreatedTsField, CreatedByField))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if col.versioned {
+		d, err = mergeUpdateOperator(d, "$inc", bson.D{{Key: VersionField, Value: int64(1)}})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// mergeUpdateOperator sets fields in the operator of the update document,
+// leaving out the fields already updated by another operator, so that the
+// update does not conflict.
+func mergeUpdateOperator(update bson.D, op string, fields bson.D) (_update bson.D, err error) {
+	for _, e := range update {
+		if !strings.HasPrefix(e.Key, "$") {
+			// replacement documents are not allowed in updates, let the
+			// driver report it
+			return update, nil
+		}
+	}
+	updated := map[string]bool{}
+	for _, e := range update {
+		if e.Key == op {
+			continue
+		}
+		d, err := toBsonD(e.Value)
+		if err != nil {
+			continue
+		}
+		for _, f := range d {
+			updated[f.Key] = true
+		}
+	}
+	var _fields bson.D
+	for _, f := range fields {
+		if !updated[f.Key] {
+			_fields = append(_fields, f)
+		}
+	}
+	for i, e := range update {
+		if e.Key != op {
+			continue
+		}
+		d, err := toBsonD(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		update[i].Value = setBsonFields(d, _fields)
+		return update, nil
+	}
+	if len(_fields) == 0 {
+		return update, nil
+	}
+	return append(update, bson.E{Key: op, Value: _fields}), nil
+}
+
+// toBsonD converts a document, e.g. a struct, a map or bson.D, to bson.D.
+func toBsonD(doc interface{}) (d bson.D, err error) {
+	if d, ok := doc.(bson.D); ok {
+		return append(bson.D{}, d...), nil
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if err := bson.Unmarshal(data, &d); err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return d, nil
+}
+
+// setBsonFields sets the fields in d, replacing existing ones in place.
+func setBsonFields(d bson.D, fields bson.D) bson.D {
+	for _, f := range fields {
+		found := false
+		for i, e := range d {
+			if e.Key == f.Key {
+				d[i].Value = f.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			d = append(d, f)
+		}
+	}
+	return d
+}
+
+// getBsonInt returns the integer value of the field of d.
+func getBsonInt(d bson.D, key string) (value int64, ok bool) {
+	for _, e := range d {
+		if e.Key != key {
+			continue
+		}
+		switch v := e.Value.(type) {
+		case int:
+			return int64(v), true
+		case int32:
+			return int64(v), true
+		case int64:
+			return v, true
+		case float64:
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+func isBsonDocument(v interface{}) bool {
+	switch v.(type) {
+	case bson.D, bson.Raw:
+		return true
+	default:
+		return false
+	}
+}
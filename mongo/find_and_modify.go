@@ -0,0 +1,166 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type FindAndModifyOptions struct {
+	// Sort picks the document to modify among the matching ones.
+	Sort       bson.D
+	Projection interface{}
+
+	// Upsert inserts a document if none matches. It does not apply to
+	// FindOneAndDelete.
+	Upsert bool
+
+	// ReturnAfter returns the document after the change instead of before
+	// it. It does not apply to FindOneAndDelete.
+	ReturnAfter bool
+
+	Collation *options.Collation
+	MaxTime   time.Duration
+}
+
+func (opts *FindAndModifyOptions) getReturnDocument() (rd options.ReturnDocument) {
+	if opts.ReturnAfter {
+		return options.After
+	}
+	return options.Before
+}
+
+func (opts *FindAndModifyOptions) toFindOneAndUpdateOptions() (_opts *options.FindOneAndUpdateOptions) {
+	_opts = options.FindOneAndUpdate().SetReturnDocument(opts.getReturnDocument())
+	if opts.Sort != nil {
+		_opts.SetSort(opts.Sort)
+	}
+	if opts.Projection != nil {
+		_opts.SetProjection(opts.Projection)
+	}
+	if opts.Upsert {
+		_opts.SetUpsert(true)
+	}
+	if opts.Collation != nil {
+		_opts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		_opts.SetMaxTime(opts.MaxTime)
+	}
+	return _opts
+}
+
+func (opts *FindAndModifyOptions) toFindOneAndReplaceOptions() (_opts *options.FindOneAndReplaceOptions) {
+	_opts = options.FindOneAndReplace().SetReturnDocument(opts.getReturnDocument())
+	if opts.Sort != nil {
+		_opts.SetSort(opts.Sort)
+	}
+	if opts.Projection != nil {
+		_opts.SetProjection(opts.Projection)
+	}
+	if opts.Upsert {
+		_opts.SetUpsert(true)
+	}
+	if opts.Collation != nil {
+		_opts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		_opts.SetMaxTime(opts.MaxTime)
+	}
+	return _opts
+}
+
+func (opts *FindAndModifyOptions) toFindOneAndDeleteOptions() (_opts *options.FindOneAndDeleteOptions) {
+	_opts = options.FindOneAndDelete()
+	if opts.Sort != nil {
+		_opts.SetSort(opts.Sort)
+	}
+	if opts.Projection != nil {
+		_opts.SetProjection(opts.Projection)
+	}
+	if opts.Collation != nil {
+		_opts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		_opts.SetMaxTime(opts.MaxTime)
+	}
+	return _opts
+}
+
+// FindOneAndUpdate atomically updates the first document matching query and
+// returns it, e.g. to claim the next pending task. The result returns
+// mongo.ErrNoDocuments if no document matched and none was upserted.
+func (col *Col) FindOneAndUpdate(query bson.M, update interface{}, opts *FindAndModifyOptions) (fr *FindResult) {
+	if opts == nil {
+		opts = &FindAndModifyOptions{}
+	}
+	update, err := col.getUpdate(update, opts.Upsert)
+	if err != nil {
+		return &FindResult{
+			col: col,
+			err: err,
+		}
+	}
+	res := col.c.FindOneAndUpdate(col.ctx, col.getQuery(query), update, opts.toFindOneAndUpdateOptions())
+	return col.getSingleFindResult(res)
+}
+
+// FindOneAndReplace atomically replaces the first document matching query
+// and returns it. In versioned collections, the document must be at the
+// version of doc.
+func (col *Col) FindOneAndReplace(query bson.M, doc interface{}, opts *FindAndModifyOptions) (fr *FindResult) {
+	if opts == nil {
+		opts = &FindAndModifyOptions{}
+	}
+	query, doc, err := col.getReplace(col.getQuery(query), doc)
+	if err != nil {
+		return &FindResult{
+			col: col,
+			err: err,
+		}
+	}
+	res := col.c.FindOneAndReplace(col.ctx, query, doc, opts.toFindOneAndReplaceOptions())
+	return col.getSingleFindResult(res)
+}
+
+// FindOneAndDelete atomically deletes the first document matching query and
+// returns it. In soft delete mode, the document is marked as deleted.
+func (col *Col) FindOneAndDelete(query bson.M, opts *FindAndModifyOptions) (fr *FindResult) {
+	if opts == nil {
+		opts = &FindAndModifyOptions{}
+	}
+	if col.softDelete {
+		_opts := *opts
+		_opts.Upsert = false
+		_opts.ReturnAfter = false
+		res := col.c.FindOneAndUpdate(col.ctx, col.getQuery(query), col.getSoftDeleteUpdate(), _opts.toFindOneAndUpdateOptions())
+		return col.getSingleFindResult(res)
+	}
+	res := col.c.FindOneAndDelete(col.ctx, col.getQuery(query), opts.toFindOneAndDeleteOptions())
+	return col.getSingleFindResult(res)
+}
+
+// Distinct returns the distinct values of the field among the documents
+// matching query.
+func (col *Col) Distinct(fieldName string, query bson.M) (values []interface{}, err error) {
+	values, err = col.c.Distinct(col.ctx, fieldName, col.getQuery(query))
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return values, nil
+}
+
+func (col *Col) getSingleFindResult(res *mongo.SingleResult) (fr *FindResult) {
+	if res.Err() != nil {
+		return &FindResult{
+			col: col,
+			err: res.Err(),
+		}
+	}
+	return &FindResult{
+		col: col,
+		res: res,
+	}
+}
@@ -0,0 +1,125 @@
+package mongo
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestCol_FindOneAndUpdate(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	_, err = to.col.InsertMany([]interface{}{
+		TestDocument{Key: "pending", Value: 2},
+		TestDocument{Key: "pending", Value: 1},
+	})
+	require.Nil(t, err)
+
+	// claim the pending document with the lowest value
+	var doc TestDocument
+	err = to.col.FindOneAndUpdate(bson.M{"key": "pending"}, bson.M{"$set": bson.M{"key": "running"}}, &FindAndModifyOptions{
+		Sort:        bson.D{{Key: "value", Value: 1}},
+		ReturnAfter: true,
+	}).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "running", doc.Key)
+	require.Equal(t, 1, doc.Value)
+
+	// before
+	err = to.col.FindOneAndUpdate(bson.M{"key": "pending"}, bson.M{"$set": bson.M{"key": "running"}}, nil).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "pending", doc.Key)
+
+	// none left
+	err = to.col.FindOneAndUpdate(bson.M{"key": "pending"}, bson.M{"$set": bson.M{"key": "running"}}, nil).One(&doc)
+	require.Equal(t, mongo.ErrNoDocuments, err)
+
+	// upsert
+	err = to.col.FindOneAndUpdate(bson.M{"key": "new"}, bson.M{"$set": bson.M{"value": 3}}, &FindAndModifyOptions{
+		Upsert:      true,
+		ReturnAfter: true,
+		Projection:  bson.M{"value": 1},
+	}).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, 3, doc.Value)
+	require.Empty(t, doc.Key)
+
+	cleanupColTest(to)
+}
+
+func TestCol_FindOneAndReplace(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	_, err = to.col.Insert(TestDocument{Key: "a", Value: 1})
+	require.Nil(t, err)
+
+	var doc TestDocument
+	err = to.col.FindOneAndReplace(bson.M{"key": "a"}, TestDocument{Key: "b", Value: 2}, &FindAndModifyOptions{
+		ReturnAfter: true,
+	}).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "b", doc.Key)
+	require.Equal(t, 2, doc.Value)
+
+	cleanupColTest(to)
+}
+
+func TestCol_FindOneAndDelete(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	_, err = to.col.InsertMany([]interface{}{
+		TestDocument{Key: "a", Value: 1},
+		TestDocument{Key: "b", Value: 2},
+	})
+	require.Nil(t, err)
+
+	var doc TestDocument
+	err = to.col.FindOneAndDelete(bson.M{}, &FindAndModifyOptions{
+		Sort: bson.D{{Key: "value", Value: -1}},
+	}).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "b", doc.Key)
+	total, err := to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 1, total)
+
+	// soft delete
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColSoftDelete())
+	err = col.FindOneAndDelete(bson.M{"key": "a"}, nil).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "a", doc.Key)
+	total, err = col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+	total, err = to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 1, total)
+
+	cleanupColTest(to)
+}
+
+func TestCol_Distinct(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	_, err = to.col.InsertMany([]interface{}{
+		TestDocument{Key: "a", Value: 1},
+		TestDocument{Key: "b", Value: 1},
+		TestDocument{Key: "a", Value: 2},
+	})
+	require.Nil(t, err)
+
+	values, err := to.col.Distinct("key", nil)
+	require.Nil(t, err)
+	require.ElementsMatch(t, []interface{}{"a", "b"}, values)
+
+	values, err = to.col.Distinct("key", bson.M{"value": 2})
+	require.Nil(t, err)
+	require.Equal(t, []interface{}{"a"}, values)
+
+	cleanupColTest(to)
+}
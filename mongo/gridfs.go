@@ -0,0 +1,167 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"time"
+)
+
+// BucketFile is a document of the files collection of a bucket.
+type BucketFile struct {
+	Id         primitive.ObjectID `bson:"_id" json:"_id"`
+	Filename   string             `bson:"filename" json:"filename"`
+	Length     int64              `bson:"length" json:"length"`
+	ChunkSize  int32              `bson:"chunkSize" json:"chunk_size"`
+	UploadDate time.Time          `bson:"uploadDate" json:"upload_date"`
+	Metadata   bson.M             `bson:"metadata,omitempty" json:"metadata,omitempty"`
+}
+
+// Bucket stores files in GridFS, e.g. spider files and large crawled
+// artifacts that must be shared between nodes.
+type Bucket struct {
+	b     *gridfs.Bucket
+	files *Col
+}
+
+// Upload stores the content of r as a new file and returns its id.
+func (b *Bucket) Upload(filename string, r io.Reader, metadata bson.M) (id primitive.ObjectID, err error) {
+	id, err = b.b.UploadFromStream(filename, r, getUploadOptions(metadata))
+	if err != nil {
+		return id, trace.TraceError(err)
+	}
+	return id, nil
+}
+
+// OpenUploadStream returns a stream to write a new file to. The file is
+// stored when the stream is closed, and discarded if it is aborted.
+func (b *Bucket) OpenUploadStream(filename string, metadata bson.M) (us *gridfs.UploadStream, err error) {
+	us, err = b.b.OpenUploadStream(filename, getUploadOptions(metadata))
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return us, nil
+}
+
+// Download writes the content of the file to w and returns its size. It
+// returns gridfs.ErrFileNotFound if the file does not exist.
+func (b *Bucket) Download(id primitive.ObjectID, w io.Writer) (n int64, err error) {
+	n, err = b.b.DownloadToStream(id, w)
+	if err != nil {
+		return n, trace.TraceError(err)
+	}
+	return n, nil
+}
+
+// OpenDownloadStream returns a stream to read the file from, which must be
+// closed. It returns gridfs.ErrFileNotFound if the file does not exist.
+func (b *Bucket) OpenDownloadStream(id primitive.ObjectID) (ds *gridfs.DownloadStream, err error) {
+	ds, err = b.b.OpenDownloadStream(id)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return ds, nil
+}
+
+// GetFile returns the file, or mongo.ErrNoDocuments if it does not exist.
+func (b *Bucket) GetFile(id primitive.ObjectID) (file *BucketFile, err error) {
+	file = &BucketFile{}
+	if err := b.files.FindId(id).One(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// List returns the files matching the generic list query, e.g.
+// {Key: "metadata.spider_id", Op: generic.OpEqual, Value: id}.
+func (b *Bucket) List(query generic.ListQuery, opts *FindOptions) (files []BucketFile, err error) {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.files.Find(q, opts).All(&files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Delete removes the file and its chunks. It returns gridfs.ErrFileNotFound
+// if the file does not exist.
+func (b *Bucket) Delete(id primitive.ObjectID) (err error) {
+	if err := b.b.Delete(id); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// Rename sets the filename of the file. It returns gridfs.ErrFileNotFound if
+// the file does not exist.
+func (b *Bucket) Rename(id primitive.ObjectID, filename string) (err error) {
+	if err := b.b.Rename(id, filename); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// Drop removes all the files of the bucket.
+func (b *Bucket) Drop() (err error) {
+	if err := b.b.Drop(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func (b *Bucket) GetBucket() (_b *gridfs.Bucket) {
+	return b.b
+}
+
+// GetMongoBucket returns the bucket of the default database named by the
+// mongo.gridfs.bucket config, unless set by the options.
+func GetMongoBucket(opts ...BucketOption) (b *Bucket, err error) {
+	_opts := &BucketOptions{}
+	for _, op := range opts {
+		op(_opts)
+	}
+	if _opts.name == "" {
+		_opts.name = viper.GetString("mongo.gridfs.bucket")
+	}
+	if _opts.name == "" {
+		_opts.name = options.DefaultName
+	}
+	if _opts.chunkSize < 0 {
+		return nil, trace.TraceError(errors.ErrInvalidOptions)
+	}
+	if _opts.db == nil {
+		_opts.db, err = GetMongoDbWithError("")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bucketOpts := options.GridFSBucket().SetName(_opts.name)
+	if _opts.chunkSize > 0 {
+		bucketOpts.SetChunkSizeBytes(_opts.chunkSize)
+	}
+	_b, err := gridfs.NewBucket(_opts.db, bucketOpts)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return &Bucket{
+		b:     _b,
+		files: GetMongoColWithDb(_opts.name+".files", _opts.db),
+	}, nil
+}
+
+func getUploadOptions(metadata bson.M) (opts *options.UploadOptions) {
+	opts = options.GridFSUpload()
+	if metadata != nil {
+		opts.SetMetadata(metadata)
+	}
+	return opts
+}
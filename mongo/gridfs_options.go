@@ -0,0 +1,34 @@
+package mongo
+
+import "go.mongodb.org/mongo-driver/mongo"
+
+type BucketOption func(options *BucketOptions)
+
+type BucketOptions struct {
+	db        *mongo.Database
+	name      string
+	chunkSize int32
+}
+
+// WithBucketDb stores the files in db instead of the default database.
+func WithBucketDb(db *mongo.Database) BucketOption {
+	return func(options *BucketOptions) {
+		options.db = db
+	}
+}
+
+// WithBucketName sets the name of the bucket, which prefixes its files and
+// chunks collections. It defaults to the mongo.gridfs.bucket config, or
+// "fs".
+func WithBucketName(name string) BucketOption {
+	return func(options *BucketOptions) {
+		options.name = name
+	}
+}
+
+// WithBucketChunkSize sets the size in bytes of the chunks of uploaded files.
+func WithBucketChunkSize(chunkSize int32) BucketOption {
+	return func(options *BucketOptions) {
+		options.chunkSize = chunkSize
+	}
+}
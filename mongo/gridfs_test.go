@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"bytes"
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"io"
+	"testing"
+)
+
+func TestGetMongoBucket(t *testing.T) {
+	c, err := GetMongoClient(WithClientName("test_gridfs"), WithHost("test-host"))
+	require.Nil(t, err)
+	db := c.Database("test_db")
+
+	b, err := GetMongoBucket(WithBucketDb(db))
+	require.Nil(t, err)
+	require.Equal(t, "fs.files", b.GetBucket().GetFilesCollection().Name())
+
+	viper.Set("mongo.gridfs.bucket", "artifacts")
+	defer viper.Set("mongo.gridfs.bucket", nil)
+	b, err = GetMongoBucket(WithBucketDb(db))
+	require.Nil(t, err)
+	require.Equal(t, "artifacts.chunks", b.GetBucket().GetChunksCollection().Name())
+
+	b, err = GetMongoBucket(WithBucketDb(db), WithBucketName("spiders"))
+	require.Nil(t, err)
+	require.Equal(t, "spiders.files", b.files.c.Name())
+
+	_, err = GetMongoBucket(WithBucketDb(db), WithBucketChunkSize(-1))
+	require.True(t, goerrors.Is(err, errors.ErrInvalidOptions))
+}
+
+func TestBucket(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	b, err := GetMongoBucket()
+	require.Nil(t, err)
+
+	// upload
+	id, err := b.Upload("a.txt", bytes.NewBufferString("hello"), bson.M{"spider": "s1"})
+	require.Nil(t, err)
+	us, err := b.OpenUploadStream("b.txt", bson.M{"spider": "s2"})
+	require.Nil(t, err)
+	_, err = us.Write([]byte("world"))
+	require.Nil(t, err)
+	require.Nil(t, us.Close())
+
+	// download
+	var buf bytes.Buffer
+	n, err := b.Download(id, &buf)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), n)
+	require.Equal(t, "hello", buf.String())
+	ds, err := b.OpenDownloadStream(id)
+	require.Nil(t, err)
+	data, err := io.ReadAll(ds)
+	require.Nil(t, err)
+	require.Nil(t, ds.Close())
+	require.Equal(t, "hello", string(data))
+
+	// list
+	files, err := b.List(generic.ListQuery{{Key: "metadata.spider", Op: generic.OpEqual, Value: "s1"}}, nil)
+	require.Nil(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, id, files[0].Id)
+	require.Equal(t, "a.txt", files[0].Filename)
+	require.Equal(t, "s1", files[0].Metadata["spider"])
+	files, err = b.List(nil, &FindOptions{Sort: bson.D{{Key: "filename", Value: -1}}})
+	require.Nil(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "b.txt", files[0].Filename)
+
+	// rename
+	err = b.Rename(id, "c.txt")
+	require.Nil(t, err)
+	file, err := b.GetFile(id)
+	require.Nil(t, err)
+	require.Equal(t, "c.txt", file.Filename)
+
+	// delete
+	err = b.Delete(id)
+	require.Nil(t, err)
+	_, err = b.GetFile(id)
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	err = b.Delete(id)
+	require.True(t, goerrors.Is(err, gridfs.ErrFileNotFound))
+	_, err = b.Download(id, &buf)
+	require.True(t, goerrors.Is(err, gridfs.ErrFileNotFound))
+
+	cleanupColTest(to)
+}
@@ -0,0 +1,407 @@
+package mongo
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	IndexSyncActionCreate  = "create"
+	IndexSyncActionDrop    = "drop"
+	IndexSyncActionRebuild = "rebuild"
+)
+
+const defaultIndexLanguage = "english"
+
+// IndexSpec declares an index of a collection.
+type IndexSpec struct {
+	// Name defaults to the name generated by mongo, e.g. "key_1_value_-1".
+	Name string
+
+	// Keys are the indexed fields in order. Use "text" as the value of the
+	// fields of a text index.
+	Keys bson.D
+
+	Unique bool
+	Sparse bool
+
+	// ExpireAfterSeconds makes a TTL index.
+	ExpireAfterSeconds *int32
+
+	// PartialFilterExpression makes a partial index.
+	PartialFilterExpression interface{}
+
+	// Weights and DefaultLanguage apply to text indexes.
+	Weights         bson.M
+	DefaultLanguage string
+}
+
+func (spec *IndexSpec) GetName() (name string) {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	var parts []string
+	for _, e := range spec.Keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", e.Key, e.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+func (spec *IndexSpec) isText() bool {
+	for _, e := range spec.Keys {
+		if e.Value == "text" {
+			return true
+		}
+	}
+	return false
+}
+
+func (spec *IndexSpec) toIndexModel() (model mongo.IndexModel) {
+	opts := options.Index().SetName(spec.GetName())
+	if spec.Unique {
+		opts.SetUnique(true)
+	}
+	if spec.Sparse {
+		opts.SetSparse(true)
+	}
+	if spec.ExpireAfterSeconds != nil {
+		opts.SetExpireAfterSeconds(*spec.ExpireAfterSeconds)
+	}
+	if spec.PartialFilterExpression != nil {
+		opts.SetPartialFilterExpression(spec.PartialFilterExpression)
+	}
+	if spec.Weights != nil {
+		opts.SetWeights(spec.Weights)
+	}
+	if spec.DefaultLanguage != "" {
+		opts.SetDefaultLanguage(spec.DefaultLanguage)
+	}
+	return mongo.IndexModel{
+		Keys:    spec.Keys,
+		Options: opts,
+	}
+}
+
+// getIndexDocument returns the spec as listed by listIndexes, with numbers
+// normalized to float64, for comparison with the existing indexes.
+func (spec *IndexSpec) getIndexDocument() (doc map[string]interface{}) {
+	doc = map[string]interface{}{
+		"name": spec.GetName(),
+	}
+
+	// keys
+	if spec.isText() {
+		// text fields are stored as _fts/_ftsx keys and weights
+		var key bson.D
+		weights := map[string]interface{}{}
+		for _, e := range spec.Keys {
+			if e.Value != "text" {
+				key = append(key, e)
+				continue
+			}
+			if len(weights) == 0 {
+				key = append(key, bson.E{Key: "_fts", Value: "text"}, bson.E{Key: "_ftsx", Value: 1})
+			}
+			weights[e.Key] = 1
+		}
+		for k, v := range spec.Weights {
+			weights[k] = v
+		}
+		doc["key"] = normalizeIndexKey(key)
+		doc["weights"] = normalizeIndexValue(weights)
+		doc["default_language"] = defaultIndexLanguage
+		if spec.DefaultLanguage != "" {
+			doc["default_language"] = spec.DefaultLanguage
+		}
+	} else {
+		doc["key"] = normalizeIndexKey(spec.Keys)
+	}
+
+	// options
+	if spec.Unique {
+		doc["unique"] = true
+	}
+	if spec.Sparse {
+		doc["sparse"] = true
+	}
+	if spec.ExpireAfterSeconds != nil {
+		doc["expireAfterSeconds"] = normalizeIndexValue(*spec.ExpireAfterSeconds)
+	}
+	if spec.PartialFilterExpression != nil {
+		doc["partialFilterExpression"] = normalizeIndexValue(spec.PartialFilterExpression)
+	}
+
+	return doc
+}
+
+// indexCompareFields are the listIndexes fields compared by SyncIndexes.
+var indexCompareFields = []string{
+	"key",
+	"unique",
+	"sparse",
+	"expireAfterSeconds",
+	"partialFilterExpression",
+	"weights",
+	"default_language",
+}
+
+type IndexSyncOptions struct {
+	// DryRun only reports the actions without performing them.
+	DryRun bool
+
+	// KeepUnknown keeps the indexes that are not declared instead of
+	// dropping them.
+	KeepUnknown bool
+}
+
+type IndexSyncAction struct {
+	Type   string
+	Name   string
+	Reason string
+}
+
+type IndexSyncReport struct {
+	DryRun  bool
+	Actions []IndexSyncAction
+}
+
+func (r *IndexSyncReport) String() string {
+	if len(r.Actions) == 0 {
+		return "indexes are in sync"
+	}
+	var lines []string
+	for _, a := range r.Actions {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", a.Type, a.Name, a.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SyncIndexes reconciles the indexes of the collection with specs. Missing
+// indexes are created, indexes whose definition changed are dropped and
+// created again, and undeclared indexes are dropped unless KeepUnknown is
+// set. The _id index is never touched.
+func (col *Col) SyncIndexes(specs []IndexSpec, opts *IndexSyncOptions) (report *IndexSyncReport, err error) {
+	if opts == nil {
+		opts = &IndexSyncOptions{}
+	}
+	for _, spec := range specs {
+		if len(spec.Keys) == 0 {
+			return nil, trace.TraceError(errors.ErrMissingValue)
+		}
+	}
+
+	// existing indexes
+	cur, err := col.c.Indexes().List(col.ctx)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	var existing []bson.D
+	if err := cur.All(col.ctx, &existing); err != nil {
+		return nil, trace.TraceError(err)
+	}
+
+	// diff
+	report = &IndexSyncReport{
+		DryRun:  opts.DryRun,
+		Actions: getIndexSyncActions(existing, specs, opts),
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	// drop first, so that rebuilt indexes can be created with the same name
+	// or keys
+	specsByName := map[string]IndexSpec{}
+	for _, spec := range specs {
+		specsByName[spec.GetName()] = spec
+	}
+	var models []mongo.IndexModel
+	for _, a := range report.Actions {
+		switch a.Type {
+		case IndexSyncActionDrop:
+			if err := col.DeleteIndex(a.Name); err != nil {
+				return report, err
+			}
+		case IndexSyncActionRebuild:
+			if err := col.DeleteIndex(a.Name); err != nil {
+				return report, err
+			}
+		}
+	}
+	for _, a := range report.Actions {
+		switch a.Type {
+		case IndexSyncActionCreate, IndexSyncActionRebuild:
+			spec := specsByName[a.Name]
+			models = append(models, spec.toIndexModel())
+		}
+	}
+	if len(models) > 0 {
+		if err := col.CreateIndexes(models); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+func getIndexSyncActions(existing []bson.D, specs []IndexSpec, opts *IndexSyncOptions) (actions []IndexSyncAction) {
+	// existing indexes by name, except _id
+	existingByName := map[string]map[string]interface{}{}
+	var existingNames []string
+	for _, d := range existing {
+		doc := map[string]interface{}{}
+		for _, e := range d {
+			if e.Key == "key" {
+				doc[e.Key] = normalizeIndexKey(e.Value)
+			} else {
+				doc[e.Key] = normalizeIndexValue(e.Value)
+			}
+		}
+		name, _ := doc["name"].(string)
+		if name == "_id_" {
+			continue
+		}
+		existingByName[name] = doc
+		existingNames = append(existingNames, name)
+	}
+	sort.Strings(existingNames)
+	matched := map[string]bool{}
+
+	for _, spec := range specs {
+		name := spec.GetName()
+		expected := spec.getIndexDocument()
+
+		// same name
+		if doc, ok := existingByName[name]; ok {
+			matched[name] = true
+			if diff := diffIndexDocuments(doc, expected); len(diff) > 0 {
+				actions = append(actions, IndexSyncAction{
+					Type:   IndexSyncActionRebuild,
+					Name:   name,
+					Reason: "changed " + strings.Join(diff, ", "),
+				})
+			}
+			continue
+		}
+
+		// same keys under another name, which would prevent the creation
+		for _, n := range existingNames {
+			if matched[n] || !reflect.DeepEqual(existingByName[n]["key"], expected["key"]) {
+				continue
+			}
+			matched[n] = true
+			actions = append(actions, IndexSyncAction{
+				Type:   IndexSyncActionDrop,
+				Name:   n,
+				Reason: "renamed to " + name,
+			})
+			break
+		}
+
+		actions = append(actions, IndexSyncAction{
+			Type:   IndexSyncActionCreate,
+			Name:   name,
+			Reason: "missing",
+		})
+	}
+
+	// undeclared
+	if !opts.KeepUnknown {
+		for _, n := range existingNames {
+			if matched[n] {
+				continue
+			}
+			actions = append(actions, IndexSyncAction{
+				Type:   IndexSyncActionDrop,
+				Name:   n,
+				Reason: "not declared",
+			})
+		}
+	}
+
+	return actions
+}
+
+func diffIndexDocuments(actual map[string]interface{}, expected map[string]interface{}) (fields []string) {
+	for _, f := range indexCompareFields {
+		a, b := actual[f], expected[f]
+		if f == "unique" || f == "sparse" {
+			// false is the same as unset
+			if a == false {
+				a = nil
+			}
+		}
+		if !reflect.DeepEqual(a, b) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// normalizeIndexValue converts a value to its bson form, with documents as
+// maps and all numbers as float64, so that specs compare equal to listed
+// indexes.
+func normalizeIndexValue(v interface{}) interface{} {
+	// round trip through bson to get primitive types
+	raw, err := bson.Marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return v
+	}
+	var d bson.D
+	if err := bson.Unmarshal(raw, &d); err != nil || len(d) == 0 {
+		return v
+	}
+	return normalizeBsonValue(d[0].Value)
+}
+
+// normalizeIndexKey is like normalizeIndexValue but keeps the order of the
+// fields, which matters for index keys.
+func normalizeIndexKey(v interface{}) (key []interface{}) {
+	d, ok := normalizeIndexValue(v).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var keys bson.D
+	if err := bson.Unmarshal(raw, &keys); err != nil {
+		return nil
+	}
+	for _, e := range keys {
+		key = append(key, []interface{}{e.Key, d[e.Key]})
+	}
+	return key
+}
+
+func normalizeBsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case primitive.D:
+		res := map[string]interface{}{}
+		for _, e := range value {
+			res[e.Key] = normalizeBsonValue(e.Value)
+		}
+		return res
+	case primitive.A:
+		var res []interface{}
+		for _, e := range value {
+			res = append(res, normalizeBsonValue(e))
+		}
+		return res
+	case int32:
+		return float64(value)
+	case int64:
+		return float64(value)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestGetIndexSyncActions(t *testing.T) {
+	ttl := int32(3600)
+	existing := []bson.D{
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_id", Value: int32(1)}}}, {Key: "name", Value: "_id_"}},
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "key", Value: int32(1)}}}, {Key: "name", Value: "key_1"}, {Key: "unique", Value: true}},
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "ts", Value: int32(1)}}}, {Key: "name", Value: "ts_1"}, {Key: "expireAfterSeconds", Value: int32(60)}},
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "value", Value: int32(-1)}}}, {Key: "name", Value: "value_desc"}},
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}}, {Key: "name", Value: "tags_text"}, {Key: "weights", Value: bson.D{{Key: "tags", Value: int32(1)}}}, {Key: "default_language", Value: "english"}, {Key: "language_override", Value: "language"}, {Key: "textIndexVersion", Value: int32(3)}},
+		{{Key: "v", Value: int32(2)}, {Key: "key", Value: bson.D{{Key: "old", Value: int32(1)}}}, {Key: "name", Value: "old_1"}},
+	}
+	specs := []IndexSpec{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Unique: true},
+		{Keys: bson.D{{Key: "ts", Value: 1}}, ExpireAfterSeconds: &ttl},
+		{Keys: bson.D{{Key: "value", Value: -1}}},
+		{Name: "tags_text", Keys: bson.D{{Key: "tags", Value: "text"}}},
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "value", Value: 1}}, PartialFilterExpression: bson.M{"value": bson.M{"$gt": 0}}},
+	}
+
+	actions := getIndexSyncActions(existing, specs, &IndexSyncOptions{})
+	require.Equal(t, []IndexSyncAction{
+		{Type: IndexSyncActionRebuild, Name: "ts_1", Reason: "changed expireAfterSeconds"},
+		{Type: IndexSyncActionDrop, Name: "value_desc", Reason: "renamed to value_-1"},
+		{Type: IndexSyncActionCreate, Name: "value_-1", Reason: "missing"},
+		{Type: IndexSyncActionCreate, Name: "key_1_value_1", Reason: "missing"},
+		{Type: IndexSyncActionDrop, Name: "old_1", Reason: "not declared"},
+	}, actions)
+
+	actions = getIndexSyncActions(existing, specs, &IndexSyncOptions{KeepUnknown: true})
+	require.Len(t, actions, 4)
+}
+
+func TestCol_SyncIndexes(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	ttl := int32(3600)
+	specs := []IndexSpec{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Unique: true},
+		{Keys: bson.D{{Key: "ts", Value: 1}}, ExpireAfterSeconds: &ttl},
+		{Keys: bson.D{{Key: "tags", Value: "text"}}, Weights: bson.M{"tags": 2}},
+		{Keys: bson.D{{Key: "value", Value: 1}}, PartialFilterExpression: bson.M{"value": bson.M{"$gt": 0}}},
+	}
+
+	// dry run
+	report, err := to.col.SyncIndexes(specs, &IndexSyncOptions{DryRun: true})
+	require.Nil(t, err)
+	require.True(t, report.DryRun)
+	require.Len(t, report.Actions, 4)
+	indexes, err := to.col.ListIndexes()
+	require.Nil(t, err)
+	require.Len(t, indexes, 0)
+
+	// sync
+	report, err = to.col.SyncIndexes(specs, nil)
+	require.Nil(t, err)
+	require.Len(t, report.Actions, 4)
+	indexes, err = to.col.ListIndexes()
+	require.Nil(t, err)
+	require.Len(t, indexes, 5)
+
+	// in sync
+	report, err = to.col.SyncIndexes(specs, nil)
+	require.Nil(t, err)
+	require.Len(t, report.Actions, 0)
+
+	// changed and removed
+	ttl = 60
+	report, err = to.col.SyncIndexes(specs[:2], nil)
+	require.Nil(t, err)
+	require.Len(t, report.Actions, 3)
+	indexes, err = to.col.ListIndexes()
+	require.Nil(t, err)
+	require.Len(t, indexes, 3)
+
+	cleanupColTest(to)
+}
@@ -0,0 +1,381 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMigrationColName     = "_migrations"
+	DefaultMigrationLockTimeout = 10 * time.Minute
+)
+
+// migrationLockId is the _id of the lock document in the migration
+// collection. Applied migrations use their version as _id.
+const migrationLockId = "lock"
+
+// MigrationFunc changes the schema or the data of db.
+type MigrationFunc func(ctx context.Context, db *mongo.Database) (err error)
+
+// Migration is a versioned change of the database. Migrations are applied in
+// ascending order of Version and reverted in descending order.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          MigrationFunc
+
+	// Down reverts Up. Migrations without Down cannot be reverted.
+	Down MigrationFunc
+}
+
+func (m *Migration) validate() (err error) {
+	if m.Version <= 0 || m.Up == nil {
+		return fmt.Errorf("%w: version %d", errors.ErrorMongoInvalidMigration, m.Version)
+	}
+	return nil
+}
+
+var _migrations = map[int64]Migration{}
+var _migrationsMu sync.RWMutex
+
+// RegisterMigration makes a migration available to the migrators created
+// without WithMigratorMigrations. It panics if the migration is invalid or if
+// the version is registered twice.
+func RegisterMigration(migration Migration) {
+	_migrationsMu.Lock()
+	defer _migrationsMu.Unlock()
+	if err := migration.validate(); err != nil {
+		panic(err)
+	}
+	if _, ok := _migrations[migration.Version]; ok {
+		panic(fmt.Sprintf("mongo: migration registered twice %d", migration.Version))
+	}
+	_migrations[migration.Version] = migration
+}
+
+func getRegisteredMigrations() (migrations []Migration) {
+	_migrationsMu.RLock()
+	defer _migrationsMu.RUnlock()
+	for _, m := range _migrations {
+		migrations = append(migrations, m)
+	}
+	return migrations
+}
+
+// MigrationStatus is the state of a migration. Applied migrations that are
+// no longer registered are listed with the description they were applied
+// with.
+type MigrationStatus struct {
+	Version     int64
+	Description string
+	Applied     bool
+	AppliedTs   time.Time
+}
+
+type migrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedTs   time.Time `bson:"applied_ts"`
+}
+
+// Migrator applies and reverts migrations and records the applied ones in
+// the migration collection. Each operation holds a lock in the collection,
+// so that only one node migrates at a time; the others fail with
+// errors.ErrorMongoLocked.
+//
+// Migrations are not run in transactions, so a failing migration should
+// leave the database in a state where it can be run again.
+type Migrator struct {
+	ctx         context.Context
+	db          *mongo.Database
+	col         *Col
+	migrations  []Migration
+	owner       string
+	lockTimeout time.Duration
+}
+
+// Status lists the registered and the applied migrations by version.
+func (m *Migrator) Status() (statuses []MigrationStatus, err error) {
+	records, err := m.getRecords()
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.migrations {
+		s := MigrationStatus{
+			Version:     migration.Version,
+			Description: migration.Description,
+		}
+		if r, ok := records[migration.Version]; ok {
+			s.Applied = true
+			s.AppliedTs = r.AppliedTs
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range records {
+		statuses = append(statuses, MigrationStatus{
+			Version:     r.Version,
+			Description: r.Description,
+			Applied:     true,
+			AppliedTs:   r.AppliedTs,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Up applies all pending migrations and returns their versions.
+func (m *Migrator) Up() (versions []int64, err error) {
+	return m.UpTo(0)
+}
+
+// UpTo applies the pending migrations up to and including version, or all
+// of them if version is 0, and returns their versions.
+func (m *Migrator) UpTo(version int64) (versions []int64, err error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	records, err := m.getRecords()
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.migrations {
+		if version > 0 && migration.Version > version {
+			break
+		}
+		if _, ok := records[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(m.ctx, m.db); err != nil {
+			return versions, trace.TraceError(err)
+		}
+		// the _id of the record is its version, not an ObjectID as Insert expects
+		if _, err := m.col.GetCollection().InsertOne(m.ctx, &migrationRecord{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedTs:   time.Now(),
+		}); err != nil {
+			return versions, trace.TraceError(err)
+		}
+		versions = append(versions, migration.Version)
+	}
+	return versions, nil
+}
+
+// Down reverts the last applied migration and returns its version, or nil if
+// no migration is applied.
+func (m *Migrator) Down() (versions []int64, err error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	records, err := m.getRecords()
+	if err != nil {
+		return nil, err
+	}
+	var last int64
+	for v := range records {
+		if v > last {
+			last = v
+		}
+	}
+	if last == 0 {
+		return nil, nil
+	}
+	return m.down(records, last-1)
+}
+
+// DownTo reverts the applied migrations above version, in descending order,
+// and returns their versions. DownTo(0) reverts all migrations.
+func (m *Migrator) DownTo(version int64) (versions []int64, err error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	records, err := m.getRecords()
+	if err != nil {
+		return nil, err
+	}
+	return m.down(records, version)
+}
+
+func (m *Migrator) down(records map[int64]migrationRecord, version int64) (versions []int64, err error) {
+	// applied versions above version in descending order
+	var applied []int64
+	for v := range records {
+		if v > version {
+			applied = append(applied, v)
+		}
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i] > applied[j]
+	})
+
+	// check all of them can be reverted before reverting any
+	migrations := map[int64]Migration{}
+	for _, migration := range m.migrations {
+		migrations[migration.Version] = migration
+	}
+	for _, v := range applied {
+		migration, ok := migrations[v]
+		if !ok {
+			return nil, trace.TraceError(fmt.Errorf("%w: version %d", errors.ErrorMongoUnknownMigration, v))
+		}
+		if migration.Down == nil {
+			return nil, trace.TraceError(fmt.Errorf("%w: version %d", errors.ErrorMongoIrreversibleMigration, v))
+		}
+	}
+
+	for _, v := range applied {
+		if err := migrations[v].Down(m.ctx, m.db); err != nil {
+			return versions, trace.TraceError(err)
+		}
+		if err := m.col.Delete(bson.M{"_id": v}); err != nil {
+			return versions, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// WithContext returns a copy of the migrator bound to ctx, which is also
+// passed to the migrations.
+func (m *Migrator) WithContext(ctx context.Context) (migrator *Migrator) {
+	_m := *m
+	_m.ctx = ctx
+	_m.col = m.col.WithContext(ctx)
+	return &_m
+}
+
+func (m *Migrator) getRecords() (records map[int64]migrationRecord, err error) {
+	var list []migrationRecord
+	if err := m.col.Find(bson.M{"_id": bson.M{"$ne": migrationLockId}}, nil).All(&list); err != nil {
+		return nil, err
+	}
+	records = map[int64]migrationRecord{}
+	for _, r := range list {
+		records[r.Version] = r
+	}
+	return records, nil
+}
+
+// lock takes the migration lock, taking over an expired one, and refreshes
+// it until unlock is called.
+func (m *Migrator) lock() (unlock func(), err error) {
+	now := time.Now()
+	if err := m.col.UpdateWithOptions(bson.M{
+		"_id":       migrationLockId,
+		"expire_ts": bson.M{"$lt": now},
+	}, bson.M{
+		"$set": bson.M{
+			"owner":     m.owner,
+			"expire_ts": now.Add(m.lockTimeout),
+		},
+	}, options.Update().SetUpsert(true)); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// the lock document exists and has not expired
+			return nil, trace.TraceError(errors.ErrorMongoLocked)
+		}
+		return nil, err
+	}
+
+	// refresh
+	col := m.col.WithContext(context.Background())
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.lockTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = col.Update(bson.M{"_id": migrationLockId, "owner": m.owner}, bson.M{
+					"$set": bson.M{"expire_ts": time.Now().Add(m.lockTimeout)},
+				})
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		_ = col.Delete(bson.M{"_id": migrationLockId, "owner": m.owner})
+	}, nil
+}
+
+// NewMigrator returns a migrator of the registered migrations on the default
+// database, unless configured otherwise by opts.
+func NewMigrator(opts ...MigratorOption) (m *Migrator, err error) {
+	_opts := &MigratorOptions{
+		colName:     DefaultMigrationColName,
+		lockTimeout: DefaultMigrationLockTimeout,
+	}
+	for _, op := range opts {
+		op(_opts)
+	}
+	if _opts.db == nil {
+		_opts.db, err = GetMongoDbWithError("")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _opts.owner == "" {
+		hostname, _ := os.Hostname()
+		_opts.owner = fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex())
+	}
+	if _opts.lockTimeout <= 0 {
+		return nil, trace.TraceError(errors.ErrInvalidOptions)
+	}
+
+	// migrations
+	migrations := _opts.migrations
+	if migrations == nil {
+		migrations = getRegisteredMigrations()
+	} else {
+		versions := map[int64]bool{}
+		for _, migration := range migrations {
+			if err := migration.validate(); err != nil {
+				return nil, trace.TraceError(err)
+			}
+			if versions[migration.Version] {
+				return nil, trace.TraceError(fmt.Errorf("%w: version %d registered twice", errors.ErrorMongoInvalidMigration, migration.Version))
+			}
+			versions[migration.Version] = true
+		}
+		migrations = append([]Migration{}, migrations...)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return &Migrator{
+		ctx:         context.Background(),
+		db:          _opts.db,
+		col:         GetMongoColWithDb(_opts.colName, _opts.db),
+		migrations:  migrations,
+		owner:       _opts.owner,
+		lockTimeout: _opts.lockTimeout,
+	}, nil
+}
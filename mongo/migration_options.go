@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type MigratorOption func(options *MigratorOptions)
+
+type MigratorOptions struct {
+	db          *mongo.Database
+	colName     string
+	migrations  []Migration
+	owner       string
+	lockTimeout time.Duration
+}
+
+// WithMigratorDb migrates db instead of the default database.
+func WithMigratorDb(db *mongo.Database) MigratorOption {
+	return func(options *MigratorOptions) {
+		options.db = db
+	}
+}
+
+// WithMigratorColName records the applied migrations in the collection
+// instead of DefaultMigrationColName.
+func WithMigratorColName(colName string) MigratorOption {
+	return func(options *MigratorOptions) {
+		options.colName = colName
+	}
+}
+
+// WithMigratorMigrations uses migrations instead of the ones registered with
+// RegisterMigration.
+func WithMigratorMigrations(migrations ...Migration) MigratorOption {
+	return func(options *MigratorOptions) {
+		options.migrations = migrations
+	}
+}
+
+// WithMigratorOwner identifies the node holding the migration lock. It
+// defaults to a unique id derived from the hostname and the process id.
+func WithMigratorOwner(owner string) MigratorOption {
+	return func(options *MigratorOptions) {
+		options.owner = owner
+	}
+}
+
+// WithMigratorLockTimeout sets the time after which the lock of a node that
+// stopped refreshing it is taken over.
+func WithMigratorLockTimeout(d time.Duration) MigratorOption {
+	return func(options *MigratorOptions) {
+		options.lockTimeout = d
+	}
+}
@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func getTestMigrations(colName string) []Migration {
+	return []Migration{
+		{
+			Version:     2,
+			Description: "add value",
+			Up: func(ctx context.Context, db *mongo.Database) (err error) {
+				_, err = db.Collection(colName).UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"value": 1}})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) (err error) {
+				_, err = db.Collection(colName).UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"value": ""}})
+				return err
+			},
+		},
+		{
+			Version:     1,
+			Description: "insert init",
+			Up: func(ctx context.Context, db *mongo.Database) (err error) {
+				_, err = db.Collection(colName).InsertOne(ctx, bson.M{"key": "init"})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) (err error) {
+				_, err = db.Collection(colName).DeleteMany(ctx, bson.M{"key": "init"})
+				return err
+			},
+		},
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	defer cleanupColTest(to)
+
+	m, err := NewMigrator(
+		WithMigratorDb(to.col.db),
+		WithMigratorMigrations(getTestMigrations(to.colName)...),
+	)
+	require.Nil(t, err)
+
+	// status
+	statuses, err := m.Status()
+	require.Nil(t, err)
+	require.Len(t, statuses, 2)
+	require.Equal(t, int64(1), statuses[0].Version)
+	require.False(t, statuses[0].Applied)
+
+	// up to
+	versions, err := m.UpTo(1)
+	require.Nil(t, err)
+	require.Equal(t, []int64{1}, versions)
+
+	// up
+	versions, err = m.Up()
+	require.Nil(t, err)
+	require.Equal(t, []int64{2}, versions)
+	var doc TestDocument
+	err = to.col.Find(bson.M{"key": "init"}, nil).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, 1, doc.Value)
+	statuses, err = m.Status()
+	require.Nil(t, err)
+	require.True(t, statuses[0].Applied)
+	require.True(t, statuses[1].Applied)
+
+	// up again
+	versions, err = m.Up()
+	require.Nil(t, err)
+	require.Len(t, versions, 0)
+
+	// down
+	versions, err = m.Down()
+	require.Nil(t, err)
+	require.Equal(t, []int64{2}, versions)
+	err = to.col.Find(bson.M{"key": "init"}, nil).One(&doc)
+	require.Nil(t, err)
+
+	// down to
+	versions, err = m.DownTo(0)
+	require.Nil(t, err)
+	require.Equal(t, []int64{1}, versions)
+	total, err := to.col.Count(bson.M{})
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	defer cleanupColTest(to)
+
+	m1, err := NewMigrator(WithMigratorDb(to.col.db), WithMigratorLockTimeout(time.Second))
+	require.Nil(t, err)
+	m2, err := NewMigrator(WithMigratorDb(to.col.db), WithMigratorLockTimeout(time.Second))
+	require.Nil(t, err)
+
+	unlock, err := m1.lock()
+	require.Nil(t, err)
+	_, err = m2.Up()
+	require.True(t, goerrors.Is(err, errors.ErrorMongoLocked))
+
+	// the lock is refreshed while held
+	time.Sleep(2 * time.Second)
+	_, err = m2.Up()
+	require.True(t, goerrors.Is(err, errors.ErrorMongoLocked))
+
+	unlock()
+	_, err = m2.Up()
+	require.Nil(t, err)
+}
+
+func TestNewMigrator_Invalid(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	defer cleanupColTest(to)
+
+	migrations := getTestMigrations(to.colName)
+	_, err = NewMigrator(WithMigratorDb(to.col.db), WithMigratorMigrations(migrations[0], migrations[0]))
+	require.True(t, goerrors.Is(err, errors.ErrorMongoInvalidMigration))
+
+	_, err = NewMigrator(WithMigratorDb(to.col.db), WithMigratorMigrations(Migration{Version: 1}))
+	require.True(t, goerrors.Is(err, errors.ErrorMongoInvalidMigration))
+}
@@ -0,0 +1,161 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// PageCountModeFacet finds the items and counts the total in a single
+	// $facet aggregation. The items of a page must fit in a 16MB document.
+	// The sort runs before the $facet stage so that it can use an index, but
+	// without one, all matching documents are sorted in memory and
+	// AllowDiskUse may be needed on large collections.
+	PageCountModeFacet = "facet"
+
+	// PageCountModeConcurrent runs the find and the count concurrently.
+	PageCountModeConcurrent = "concurrent"
+
+	// PageCountModeEstimated uses the estimated document count of the
+	// collection as total, ignoring the query. It is cheap on huge
+	// collections but only accurate for queries matching all documents.
+	PageCountModeEstimated = "estimated"
+)
+
+type FindPageOptions struct {
+	FindOptions
+
+	// CountMode defaults to PageCountModeFacet, which makes a single round
+	// trip. PageCountModeConcurrent runs the find and the count as separate
+	// queries, which may be faster when the query and the sort use different
+	// indexes.
+	CountMode string
+}
+
+// FindPage finds a page of the documents matching query into results and
+// returns the total number of matching documents.
+func (col *Col) FindPage(query bson.M, opts *FindPageOptions, results interface{}) (total int, err error) {
+	if opts == nil {
+		opts = &FindPageOptions{}
+	}
+	if query == nil {
+		query = bson.M{}
+	}
+	switch opts.CountMode {
+	case "", PageCountModeFacet:
+		return col.findPageFacet(query, &opts.FindOptions, results)
+	case PageCountModeConcurrent:
+		return col.findPageConcurrent(query, &opts.FindOptions, results)
+	case PageCountModeEstimated:
+		return col.findPageEstimated(query, &opts.FindOptions, results)
+	default:
+		return 0, trace.TraceError(errors.ErrInvalidOptions)
+	}
+}
+
+func (col *Col) findPageFacet(query bson.M, opts *FindOptions, results interface{}) (total int, err error) {
+	pipeline := getPageFacetPipeline(query, opts)
+	aggOpts := options.Aggregate()
+	if opts.BatchSize != 0 {
+		aggOpts.SetBatchSize(int32(opts.BatchSize))
+	}
+	if opts.Hint != nil {
+		aggOpts.SetHint(opts.Hint)
+	}
+	if opts.Collation != nil {
+		aggOpts.SetCollation(opts.Collation)
+	}
+	if opts.MaxTime != 0 {
+		aggOpts.SetMaxTime(opts.MaxTime)
+	}
+	if opts.AllowDiskUse {
+		aggOpts.SetAllowDiskUse(true)
+	}
+	var res bson.Raw
+	if err := col.Aggregate(pipeline, aggOpts).One(&res); err != nil {
+		return 0, err
+	}
+
+	// decode
+	var page struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+	}
+	if err := bson.Unmarshal(res, &page); err != nil {
+		return 0, trace.TraceError(err)
+	}
+	if err := res.Lookup("items").Unmarshal(results); err != nil {
+		return 0, trace.TraceError(err)
+	}
+	if len(page.Total) > 0 {
+		total = page.Total[0].Count
+	}
+	return total, nil
+}
+
+// getPageFacetPipeline sorts before the $facet stage, where sorts cannot use
+// indexes.
+func getPageFacetPipeline(query bson.M, opts *FindOptions) (pipeline mongo.Pipeline) {
+	pipeline = mongo.Pipeline{{{Key: "$match", Value: query}}}
+	if len(opts.Sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: opts.Sort}})
+	}
+
+	// items
+	items := mongo.Pipeline{}
+	if opts.Skip > 0 {
+		items = append(items, bson.D{{Key: "$skip", Value: int64(opts.Skip)}})
+	}
+	if opts.Limit > 0 {
+		items = append(items, bson.D{{Key: "$limit", Value: int64(opts.Limit)}})
+	}
+	if opts.Projection != nil {
+		items = append(items, bson.D{{Key: "$project", Value: opts.Projection}})
+	}
+	if len(items) == 0 {
+		// $facet sub-pipelines cannot be empty
+		items = append(items, bson.D{{Key: "$skip", Value: int64(0)}})
+	}
+
+	return append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "items", Value: items},
+		{Key: "total", Value: mongo.Pipeline{{{Key: "$count", Value: "count"}}}},
+	}}})
+}
+
+func (col *Col) findPageConcurrent(query bson.M, opts *FindOptions, results interface{}) (total int, err error) {
+	type countResult struct {
+		total int
+		err   error
+	}
+	ch := make(chan countResult, 1)
+	go func() {
+		total, err := col.Count(query)
+		ch <- countResult{total, err}
+	}()
+
+	err = col.Find(query, opts).All(results)
+	res := <-ch
+	if err != nil {
+		return 0, err
+	}
+	if res.err != nil {
+		return 0, trace.TraceError(res.err)
+	}
+	return res.total, nil
+}
+
+func (col *Col) findPageEstimated(query bson.M, opts *FindOptions, results interface{}) (total int, err error) {
+	if err := col.Find(query, opts).All(results); err != nil {
+		return 0, err
+	}
+	totalInt64, err := col.c.EstimatedDocumentCount(col.ctx)
+	if err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return int(totalInt64), nil
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestGetPageFacetPipeline(t *testing.T) {
+	pipeline := getPageFacetPipeline(bson.M{"key": "a"}, &FindOptions{
+		Sort:  bson.D{{Key: "value", Value: -1}},
+		Skip:  10,
+		Limit: 10,
+	})
+	require.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"key": "a"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "value", Value: -1}}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "items", Value: mongo.Pipeline{
+				{{Key: "$skip", Value: int64(10)}},
+				{{Key: "$limit", Value: int64(10)}},
+			}},
+			{Key: "total", Value: mongo.Pipeline{{{Key: "$count", Value: "count"}}}},
+		}}},
+	}, pipeline)
+}
+
+func TestGetPageFacetPipeline_NoOptions(t *testing.T) {
+	pipeline := getPageFacetPipeline(bson.M{}, &FindOptions{})
+	require.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "items", Value: mongo.Pipeline{{{Key: "$skip", Value: int64(0)}}}},
+			{Key: "total", Value: mongo.Pipeline{{{Key: "$count", Value: "count"}}}},
+		}}},
+	}, pipeline)
+}
+
+func TestCol_FindPage(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	var docs []interface{}
+	for i := 0; i < 25; i++ {
+		docs = append(docs, TestDocument{Key: "key", Value: i, Tags: []string{"tag"}})
+	}
+	_, err = to.col.InsertMany(docs)
+	require.Nil(t, err)
+
+	for _, mode := range []string{PageCountModeFacet, PageCountModeConcurrent} {
+		var results []TestDocument
+		total, err := to.col.FindPage(bson.M{"value": bson.M{"$gte": 5}}, &FindPageOptions{
+			FindOptions: FindOptions{
+				Skip:       10,
+				Limit:      5,
+				Sort:       bson.D{{Key: "value", Value: -1}},
+				Projection: bson.M{"tags": 0},
+			},
+			CountMode: mode,
+		}, &results)
+		require.Nil(t, err)
+		require.Equal(t, 20, total)
+		require.Len(t, results, 5)
+		require.Equal(t, 14, results[0].Value)
+		require.Empty(t, results[0].Tags)
+	}
+
+	// estimated
+	var results []TestDocument
+	total, err := to.col.FindPage(nil, &FindPageOptions{
+		FindOptions: FindOptions{Limit: 10},
+		CountMode:   PageCountModeEstimated,
+	}, &results)
+	require.Nil(t, err)
+	require.Equal(t, 25, total)
+	require.Len(t, results, 10)
+
+	// no match
+	results = nil
+	total, err = to.col.FindPage(bson.M{"key": "none"}, nil, &results)
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+	require.Len(t, results, 0)
+
+	// typed
+	typedResults, total, err := NewTypedCol[TestDocument](to.col).FindPage(nil, &FindPageOptions{
+		FindOptions: FindOptions{Limit: 3},
+	})
+	require.Nil(t, err)
+	require.Equal(t, 25, total)
+	require.Len(t, typedResults, 3)
+
+	_, err = to.col.FindPage(nil, &FindPageOptions{CountMode: "invalid"}, &results)
+	require.True(t, goerrors.Is(err, errors.ErrInvalidOptions))
+
+	cleanupColTest(to)
+}
@@ -0,0 +1,324 @@
+package mongo
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"strings"
+)
+
+// PipelineBuilder builds an aggregation pipeline stage by stage. Stages are
+// validated as they are added, and the first invalid one is reported by
+// Build.
+type PipelineBuilder struct {
+	stages mongo.Pipeline
+	err    error
+}
+
+// MergeOptions are the optional fields of a $merge stage.
+type MergeOptions struct {
+	Db             string
+	On             []string
+	Let            bson.M
+	WhenMatched    interface{}
+	WhenNotMatched string
+}
+
+// Match adds a $match stage with the generic list query.
+func (b *PipelineBuilder) Match(query generic.ListQuery) *PipelineBuilder {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return b.fail("$match", err.Error())
+	}
+	return b.Stage("$match", q)
+}
+
+// MatchFilter adds a $match stage with a mongo filter.
+func (b *PipelineBuilder) MatchFilter(filter bson.M) *PipelineBuilder {
+	if filter == nil {
+		filter = bson.M{}
+	}
+	return b.Stage("$match", filter)
+}
+
+// Group adds a $group stage grouping by id, with fields as accumulators,
+// e.g. bson.M{"count": bson.M{"$sum": 1}}.
+func (b *PipelineBuilder) Group(id interface{}, fields bson.M) *PipelineBuilder {
+	group := bson.D{{Key: "_id", Value: id}}
+	for _, k := range getSortedKeys(fields) {
+		if k == "_id" {
+			return b.fail("$group", "_id is set by id")
+		}
+		if !isOperatorDocument(fields[k]) {
+			return b.fail("$group", fmt.Sprintf("field %s is not an accumulator", k))
+		}
+		group = append(group, bson.E{Key: k, Value: fields[k]})
+	}
+	return b.Stage("$group", group)
+}
+
+func (b *PipelineBuilder) Project(projection bson.M) *PipelineBuilder {
+	if len(projection) == 0 {
+		return b.fail("$project", "empty projection")
+	}
+	return b.Stage("$project", projection)
+}
+
+// Sort adds a $sort stage. The values are 1, -1 or a $meta document.
+func (b *PipelineBuilder) Sort(sort bson.D) *PipelineBuilder {
+	if len(sort) == 0 {
+		return b.fail("$sort", "empty sort")
+	}
+	for _, e := range sort {
+		switch e.Value.(type) {
+		case bson.M, bson.D:
+			continue
+		}
+		if direction, ok := getBsonInt(bson.D{e}, e.Key); !ok || (direction != 1 && direction != -1) {
+			return b.fail("$sort", fmt.Sprintf("invalid direction of %s", e.Key))
+		}
+	}
+	return b.Stage("$sort", sort)
+}
+
+func (b *PipelineBuilder) Skip(skip int) *PipelineBuilder {
+	if skip < 0 {
+		return b.fail("$skip", "negative skip")
+	}
+	return b.Stage("$skip", int64(skip))
+}
+
+func (b *PipelineBuilder) Limit(limit int) *PipelineBuilder {
+	if limit <= 0 {
+		return b.fail("$limit", "limit must be positive")
+	}
+	return b.Stage("$limit", int64(limit))
+}
+
+// Lookup adds a $lookup stage joining the documents of the from collection
+// whose foreignField equals localField into the array field as.
+func (b *PipelineBuilder) Lookup(from, localField, foreignField, as string) *PipelineBuilder {
+	if from == "" || localField == "" || foreignField == "" || as == "" {
+		return b.fail("$lookup", "from, localField, foreignField and as are required")
+	}
+	return b.Stage("$lookup", bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: foreignField},
+		{Key: "as", Value: as},
+	})
+}
+
+// Unwind adds an $unwind stage for the array field path, with or without the
+// leading "$".
+func (b *PipelineBuilder) Unwind(path string, preserveNullAndEmptyArrays bool) *PipelineBuilder {
+	path = strings.TrimPrefix(path, "$")
+	if path == "" {
+		return b.fail("$unwind", "empty path")
+	}
+	return b.Stage("$unwind", bson.D{
+		{Key: "path", Value: "$" + path},
+		{Key: "preserveNullAndEmptyArrays", Value: preserveNullAndEmptyArrays},
+	})
+}
+
+// Facet adds a $facet stage running each sub-pipeline on the same input.
+func (b *PipelineBuilder) Facet(facets map[string]*PipelineBuilder) *PipelineBuilder {
+	if len(facets) == 0 {
+		return b.fail("$facet", "no facets")
+	}
+	facet := bson.D{}
+	for _, name := range getSortedKeys(facets) {
+		pipeline, err := facets[name].Build()
+		if err != nil {
+			return b.fail("$facet", fmt.Sprintf("facet %s: %s", name, err.Error()))
+		}
+		for _, stage := range pipeline {
+			switch stage[0].Key {
+			case "$facet", "$out", "$merge":
+				return b.fail("$facet", fmt.Sprintf("facet %s: %s is not allowed", name, stage[0].Key))
+			}
+		}
+		facet = append(facet, bson.E{Key: name, Value: pipeline})
+	}
+	return b.Stage("$facet", facet)
+}
+
+// Bucket adds a $bucket stage grouping by groupBy into the buckets delimited
+// by the sorted boundaries. Documents outside of them go to the
+// defaultBucket, unless it is nil. The count is output if output is nil.
+func (b *PipelineBuilder) Bucket(groupBy interface{}, boundaries []interface{}, defaultBucket interface{}, output bson.M) *PipelineBuilder {
+	if groupBy == nil {
+		return b.fail("$bucket", "groupBy is required")
+	}
+	if len(boundaries) < 2 {
+		return b.fail("$bucket", "at least 2 boundaries are required")
+	}
+	bucket := bson.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "boundaries", Value: boundaries},
+	}
+	if defaultBucket != nil {
+		bucket = append(bucket, bson.E{Key: "default", Value: defaultBucket})
+	}
+	if output != nil {
+		for _, k := range getSortedKeys(output) {
+			if !isOperatorDocument(output[k]) {
+				return b.fail("$bucket", fmt.Sprintf("output %s is not an accumulator", k))
+			}
+		}
+		bucket = append(bucket, bson.E{Key: "output", Value: output})
+	}
+	return b.Stage("$bucket", bucket)
+}
+
+// Out adds an $out stage writing the results to the collection, which must
+// be the last stage.
+func (b *PipelineBuilder) Out(colName string) *PipelineBuilder {
+	if colName == "" {
+		return b.fail("$out", "empty collection name")
+	}
+	return b.Stage("$out", colName)
+}
+
+// Merge adds a $merge stage merging the results into the collection, which
+// must be the last stage.
+func (b *PipelineBuilder) Merge(colName string, opts *MergeOptions) *PipelineBuilder {
+	if colName == "" {
+		return b.fail("$merge", "empty collection name")
+	}
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
+	into := interface{}(colName)
+	if opts.Db != "" {
+		into = bson.D{{Key: "db", Value: opts.Db}, {Key: "coll", Value: colName}}
+	}
+	merge := bson.D{{Key: "into", Value: into}}
+	if len(opts.On) > 0 {
+		merge = append(merge, bson.E{Key: "on", Value: opts.On})
+	}
+	if opts.Let != nil {
+		merge = append(merge, bson.E{Key: "let", Value: opts.Let})
+	}
+	if opts.WhenMatched != nil {
+		switch v := opts.WhenMatched.(type) {
+		case string:
+			switch v {
+			case "replace", "keepExisting", "merge", "fail":
+			default:
+				return b.fail("$merge", "invalid whenMatched "+v)
+			}
+		case mongo.Pipeline:
+		default:
+			return b.fail("$merge", "whenMatched is neither an action nor a pipeline")
+		}
+		merge = append(merge, bson.E{Key: "whenMatched", Value: opts.WhenMatched})
+	}
+	if opts.WhenNotMatched != "" {
+		switch opts.WhenNotMatched {
+		case "insert", "discard", "fail":
+		default:
+			return b.fail("$merge", "invalid whenNotMatched "+opts.WhenNotMatched)
+		}
+		merge = append(merge, bson.E{Key: "whenNotMatched", Value: opts.WhenNotMatched})
+	}
+	return b.Stage("$merge", merge)
+}
+
+// Stage adds a raw stage, e.g. one that has no builder method.
+func (b *PipelineBuilder) Stage(name string, value interface{}) *PipelineBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !strings.HasPrefix(name, "$") {
+		return b.fail(name, "stage names start with $")
+	}
+	if n := len(b.stages); n > 0 {
+		switch last := b.stages[n-1][0].Key; last {
+		case "$out", "$merge":
+			return b.fail(name, last+" must be the last stage")
+		}
+	}
+	b.stages = append(b.stages, bson.D{{Key: name, Value: value}})
+	return b
+}
+
+// Build returns the pipeline, or the error of the first invalid stage.
+func (b *PipelineBuilder) Build() (pipeline mongo.Pipeline, err error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return append(mongo.Pipeline{}, b.stages...), nil
+}
+
+// String returns the pipeline as indented extended JSON for debugging.
+func (b *PipelineBuilder) String() string {
+	if b.err != nil {
+		return b.err.Error()
+	}
+	var lines []string
+	for _, stage := range b.stages {
+		data, err := bson.MarshalExtJSONIndent(stage, false, false, "  ", "  ")
+		if err != nil {
+			return err.Error()
+		}
+		lines = append(lines, "  "+string(data))
+	}
+	if len(lines) == 0 {
+		return "[]"
+	}
+	return "[\n" + strings.Join(lines, ",\n") + "\n]"
+}
+
+func (b *PipelineBuilder) fail(stage string, msg string) *PipelineBuilder {
+	if b.err == nil {
+		b.err = trace.TraceError(fmt.Errorf("%w: stage %d %s: %s", errors.ErrorMongoInvalidPipeline, len(b.stages), stage, msg))
+	}
+	return b
+}
+
+func NewPipelineBuilder() (b *PipelineBuilder) {
+	return &PipelineBuilder{
+		stages: mongo.Pipeline{},
+	}
+}
+
+// AggregatePipeline runs the pipeline of the builder, see Aggregate.
+func (col *Col) AggregatePipeline(b *PipelineBuilder, opts *options.AggregateOptions) (fr *FindResult) {
+	pipeline, err := b.Build()
+	if err != nil {
+		return &FindResult{
+			col: col,
+			err: err,
+		}
+	}
+	return col.Aggregate(pipeline, opts)
+}
+
+// isOperatorDocument returns true for documents with a single operator,
+// such as accumulators.
+func isOperatorDocument(v interface{}) bool {
+	switch d := v.(type) {
+	case bson.M:
+		for k := range d {
+			return len(d) == 1 && strings.HasPrefix(k, "$")
+		}
+	case bson.D:
+		return len(d) == 1 && strings.HasPrefix(d[0].Key, "$")
+	}
+	return false
+}
+
+func getSortedKeys[V any](m map[string]V) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,111 @@
+package mongo
+
+import (
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"testing"
+)
+
+func TestPipelineBuilder(t *testing.T) {
+	b := NewPipelineBuilder().
+		Match(generic.ListQuery{{Key: "status", Op: generic.OpEqual, Value: "finished"}}).
+		Lookup("spiders", "spider_id", "_id", "spider").
+		Unwind("spider", true).
+		Group("$spider.name", bson.M{"count": bson.M{"$sum": 1}}).
+		Sort(bson.D{{Key: "count", Value: -1}}).
+		Limit(10).
+		Project(bson.M{"name": "$_id", "count": 1}).
+		Out("stats")
+	pipeline, err := b.Build()
+	require.Nil(t, err)
+	require.Equal(t, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "finished"}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "spiders"},
+			{Key: "localField", Value: "spider_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "spider"},
+		}}},
+		{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$spider"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$spider.name"},
+			{Key: "count", Value: bson.M{"$sum": 1}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}}}},
+		{{Key: "$limit", Value: int64(10)}},
+		{{Key: "$project", Value: bson.M{"name": "$_id", "count": 1}}},
+		{{Key: "$out", Value: "stats"}},
+	}, pipeline)
+
+	s := b.String()
+	require.True(t, strings.HasPrefix(s, "[\n"))
+	require.Contains(t, s, `"$lookup"`)
+}
+
+func TestPipelineBuilder_FacetBucketMerge(t *testing.T) {
+	pipeline, err := NewPipelineBuilder().
+		Facet(map[string]*PipelineBuilder{
+			"total": NewPipelineBuilder().Group(nil, bson.M{"count": bson.M{"$sum": 1}}),
+			"items": NewPipelineBuilder().Skip(10).Limit(10),
+		}).
+		Bucket("$value", []interface{}{0, 10, 100}, "other", nil).
+		Merge("stats", &MergeOptions{On: []string{"_id"}, WhenMatched: "replace", WhenNotMatched: "insert"}).
+		Build()
+	require.Nil(t, err)
+	require.Len(t, pipeline, 3)
+	require.Equal(t, []string{"items", "total"}, getBsonDKeys(pipeline[0][0].Value.(bson.D)))
+	require.Equal(t, "$merge", pipeline[2][0].Key)
+}
+
+func TestPipelineBuilder_Invalid(t *testing.T) {
+	for _, b := range []*PipelineBuilder{
+		NewPipelineBuilder().Match(generic.ListQuery{{Key: "a", Op: "invalid", Value: 1}}),
+		NewPipelineBuilder().Group("$a", bson.M{"count": 1}),
+		NewPipelineBuilder().Group("$a", bson.M{"_id": bson.M{"$sum": 1}}),
+		NewPipelineBuilder().Sort(bson.D{{Key: "a", Value: 2}}),
+		NewPipelineBuilder().Limit(0),
+		NewPipelineBuilder().Lookup("spiders", "", "_id", "spider"),
+		NewPipelineBuilder().Unwind("", false),
+		NewPipelineBuilder().Facet(map[string]*PipelineBuilder{"a": NewPipelineBuilder().Out("b")}),
+		NewPipelineBuilder().Bucket("$value", []interface{}{0}, nil, nil),
+		NewPipelineBuilder().Merge("stats", &MergeOptions{WhenMatched: "update"}),
+		NewPipelineBuilder().Out("stats").Limit(1),
+		NewPipelineBuilder().Stage("match", bson.M{}),
+	} {
+		_, err := b.Build()
+		require.True(t, goerrors.Is(err, errors.ErrorMongoInvalidPipeline))
+	}
+}
+
+func TestCol_AggregatePipeline(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	var docs []interface{}
+	for i := 0; i < 10; i++ {
+		docs = append(docs, TestDocument{Key: "key", Value: i})
+	}
+	_, err = to.col.InsertMany(docs)
+	require.Nil(t, err)
+
+	var results []TestAggregateResult
+	err = to.col.AggregatePipeline(NewPipelineBuilder().
+		Match(generic.ListQuery{{Key: "value", Op: generic.OpGreaterThanEqual, Value: 5}}).
+		Group("$key", bson.M{"count": bson.M{"$sum": 1}}), nil).All(&results)
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, 5, results[0].Count)
+
+	err = to.col.AggregatePipeline(NewPipelineBuilder().Limit(-1), nil).All(&results)
+	require.True(t, goerrors.Is(err, errors.ErrorMongoInvalidPipeline))
+
+	cleanupColTest(to)
+}
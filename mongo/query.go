@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+)
+
+// GetMongoQuery translates a generic list query into a mongo filter.
+func GetMongoQuery(query generic.ListQuery) (q bson.M, err error) {
+	if err := query.Validate(); err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getMongoQuery(query), nil
+}
+
+func getMongoQuery(query generic.ListQuery) (q bson.M) {
+	q = bson.M{}
+	var and []bson.M
+	for _, cond := range query {
+		switch cond.Op {
+		case generic.OpAnd, generic.OpOr:
+			var groups []bson.M
+			for _, g := range cond.Value.([]generic.ListQuery) {
+				groups = append(groups, getMongoQuery(g))
+			}
+			if cond.Op == generic.OpAnd {
+				and = append(and, groups...)
+			} else {
+				and = append(and, bson.M{"$or": groups})
+			}
+		default:
+			op, value := getMongoOpValue(cond)
+			expr, ok := q[cond.Key].(bson.M)
+			if !ok {
+				expr = bson.M{}
+				q[cond.Key] = expr
+			}
+			if _, ok := expr[op]; ok {
+				// the same op on the same key cannot be merged
+				and = append(and, bson.M{cond.Key: bson.M{op: value}})
+				continue
+			}
+			expr[op] = value
+		}
+	}
+
+	// use the short form for plain equality
+	for key, expr := range q {
+		m := expr.(bson.M)
+		if value, ok := m["$eq"]; ok && len(m) == 1 {
+			q[key] = value
+		}
+	}
+
+	if len(and) > 0 {
+		q["$and"] = and
+	}
+
+	return q
+}
+
+func getMongoOpValue(cond generic.ListQueryCondition) (op string, value interface{}) {
+	switch cond.Op {
+	case generic.OpContains:
+		return "$regex", primitive.Regex{Pattern: regexp.QuoteMeta(cond.Value.(string))}
+	case generic.OpStartsWith:
+		return "$regex", primitive.Regex{Pattern: "^" + regexp.QuoteMeta(cond.Value.(string))}
+	case generic.OpRegex:
+		return "$regex", primitive.Regex{Pattern: cond.Value.(string)}
+	default:
+		// eq, ne, gt, gte, lt, lte, in, nin and exists map to the mongo op of the same name
+		return "$" + cond.Op, cond.Value
+	}
+}
+
+// GetMongoFindOptions translates generic list options into mongo find options.
+func GetMongoFindOptions(opts *generic.ListOptions) (fo *FindOptions, err error) {
+	if opts == nil {
+		return nil, nil
+	}
+	fo = &FindOptions{
+		Skip:  opts.Skip,
+		Limit: opts.Limit,
+	}
+	for _, s := range opts.Sort {
+		switch s.Direction {
+		case generic.SortDirectionAsc:
+			fo.Sort = append(fo.Sort, bson.E{Key: s.Key, Value: 1})
+		case generic.SortDirectionDesc:
+			fo.Sort = append(fo.Sort, bson.E{Key: s.Key, Value: -1})
+		default:
+			return nil, trace.TraceError(errors.ErrInvalidSort)
+		}
+	}
+	return fo, nil
+}
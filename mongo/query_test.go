@@ -0,0 +1,51 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestGetMongoQuery(t *testing.T) {
+	q, err := GetMongoQuery(generic.ListQuery{
+		{Key: "status", Op: generic.OpEqual, Value: "running"},
+		{Key: "count", Op: generic.OpGreaterThan, Value: 1},
+		{Key: "count", Op: generic.OpLessThanEqual, Value: 10},
+		{Key: "tags", Op: generic.OpIn, Value: []string{"a"}},
+		{Key: "name", Op: generic.OpStartsWith, Value: "a.b"},
+		{Key: "name", Op: generic.OpContains, Value: "c"},
+		{Op: generic.OpOr, Value: []generic.ListQuery{
+			{{Key: "error", Op: generic.OpExists, Value: false}},
+			{{Key: "error", Op: generic.OpEqual, Value: ""}},
+		}},
+	})
+	require.Nil(t, err)
+	require.Equal(t, bson.M{
+		"status": "running",
+		"count":  bson.M{"$gt": 1, "$lte": 10},
+		"tags":   bson.M{"$in": []string{"a"}},
+		"name":   bson.M{"$regex": primitive.Regex{Pattern: `^a\.b`}},
+		"$and": []bson.M{
+			{"name": bson.M{"$regex": primitive.Regex{Pattern: "c"}}},
+			{"$or": []bson.M{
+				{"error": bson.M{"$exists": false}},
+				{"error": ""},
+			}},
+		},
+	}, q)
+}
+
+func TestGetMongoQuery_Invalid(t *testing.T) {
+	_, err := GetMongoQuery(generic.ListQuery{{Key: "count", Op: generic.OpGreaterThan, Value: true}})
+	require.NotNil(t, err)
+
+	// operator expressions as values are not turned into operators
+	_, err = GetMongoQuery(generic.ListQuery{{Key: "owner", Op: generic.OpEqual, Value: map[string]interface{}{"$ne": "x"}}})
+	require.NotNil(t, err)
+	_, err = GetMongoQuery(generic.ListQuery{{Key: "owner", Op: generic.OpEqual, Value: bson.M{"$ne": "x"}}})
+	require.NotNil(t, err)
+	_, err = GetMongoQuery(generic.ListQuery{{Key: "owner", Op: generic.OpEqual, Value: bson.D{{Key: "$ne", Value: "x"}}}})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"context"
+	"github.com/crawlab-team/crawlab-db"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository is the mongo implementation of db.Repository.
+type Repository struct {
+	col *Col
+}
+
+func (r *Repository) Insert(doc interface{}) (id interface{}, err error) {
+	return r.col.Insert(doc)
+}
+
+func (r *Repository) Get(id interface{}, result interface{}) (err error) {
+	oid, err := r.getObjectId(id)
+	if err != nil {
+		return err
+	}
+	return r.col.FindId(oid).One(result)
+}
+
+func (r *Repository) List(query generic.ListQuery, opts *generic.ListOptions, results interface{}) (err error) {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return err
+	}
+	fo, err := GetMongoFindOptions(opts)
+	if err != nil {
+		return err
+	}
+	return r.col.Find(q, fo).All(results)
+}
+
+func (r *Repository) Count(query generic.ListQuery) (total int, err error) {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return r.col.Count(q)
+}
+
+func (r *Repository) Update(query generic.ListQuery, values map[string]interface{}) (err error) {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return err
+	}
+	return r.col.Update(q, bson.M{"$set": values})
+}
+
+func (r *Repository) Delete(query generic.ListQuery) (err error) {
+	q, err := GetMongoQuery(query)
+	if err != nil {
+		return err
+	}
+	return r.col.Delete(q)
+}
+
+// WithContext returns a copy of the repository bound to ctx.
+func (r *Repository) WithContext(ctx context.Context) (repo *Repository) {
+	return NewMongoRepository(r.col.WithContext(ctx))
+}
+
+func (r *Repository) GetCol() (col *Col) {
+	return r.col
+}
+
+func (r *Repository) getObjectId(id interface{}) (oid primitive.ObjectID, err error) {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v, nil
+	case string:
+		oid, err = primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return primitive.NilObjectID, trace.TraceError(err)
+		}
+		return oid, nil
+	default:
+		return primitive.NilObjectID, trace.TraceError(errors.ErrInvalidType)
+	}
+}
+
+func NewMongoRepository(col *Col) (r *Repository) {
+	return &Repository{
+		col: col,
+	}
+}
+
+func GetMongoRepository(colName string, opts ...ColOption) (r db.Repository) {
+	return NewMongoRepository(GetMongoCol(colName, opts...))
+}
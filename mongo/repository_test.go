@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRepository_Insert_Get(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	r := NewMongoRepository(to.col)
+	id, err := r.Insert(TestDocument{Key: "value"})
+	require.Nil(t, err)
+
+	var doc TestDocument
+	err = r.Get(id, &doc)
+	require.Nil(t, err)
+	require.Equal(t, "value", doc.Key)
+
+	cleanupColTest(to)
+}
+
+func TestRepository_List_Count(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	r := NewMongoRepository(to.col)
+	n := 10
+	for i := 0; i < n; i++ {
+		_, err = r.Insert(TestDocument{Key: fmt.Sprintf("value-%d", i%2), Value: i})
+		require.Nil(t, err)
+	}
+
+	query := generic.ListQuery{{Key: "key", Op: generic.OpEqual, Value: "value-0"}}
+	total, err := r.Count(query)
+	require.Nil(t, err)
+	require.Equal(t, n/2, total)
+
+	var docs []TestDocument
+	err = r.List(query, &generic.ListOptions{
+		Limit: 2,
+		Sort:  []generic.ListSort{{Key: "value", Direction: generic.SortDirectionDesc}},
+	}, &docs)
+	require.Nil(t, err)
+	require.Len(t, docs, 2)
+	require.Equal(t, n-2, docs[0].Value)
+
+	cleanupColTest(to)
+}
+
+func TestRepository_Update_Delete(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	r := NewMongoRepository(to.col)
+	_, err = r.Insert(TestDocument{Key: "old-value"})
+	require.Nil(t, err)
+
+	err = r.Update(generic.ListQuery{{Key: "key", Op: generic.OpEqual, Value: "old-value"}}, map[string]interface{}{"key": "new-value"})
+	require.Nil(t, err)
+
+	total, err := r.Count(generic.ListQuery{{Key: "key", Op: generic.OpEqual, Value: "new-value"}})
+	require.Nil(t, err)
+	require.Equal(t, 1, total)
+
+	err = r.Delete(generic.ListQuery{{Key: "key", Op: generic.OpEqual, Value: "new-value"}})
+	require.Nil(t, err)
+
+	total, err = r.Count(nil)
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+
+	cleanupColTest(to)
+}
@@ -3,12 +3,18 @@ package mongo
 import (
 	"context"
 	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type FindResultInterface interface {
 	One(val interface{}) (err error)
 	All(val interface{}) (err error)
+	Next() (ok bool)
+	Decode(val interface{}) (err error)
+	Close() (err error)
+	Stream(ctx context.Context) (docs <-chan bson.Raw, errs <-chan error)
 	GetCol() (col *Col)
 	GetSingleResult() (res *mongo.SingleResult)
 	GetCursor() (cur *mongo.Cursor)
@@ -33,8 +39,13 @@ type FindResult struct {
 }
 
 func (fr *FindResult) GetError() (err error) {
-	//TODO implement me
-	panic("implement me")
+	if fr.err != nil {
+		return fr.err
+	}
+	if fr.cur != nil {
+		return fr.cur.Err()
+	}
+	return nil
 }
 
 func (fr *FindResult) One(val interface{}) (err error) {
@@ -42,7 +53,8 @@ func (fr *FindResult) One(val interface{}) (err error) {
 		return fr.err
 	}
 	if fr.cur != nil {
-		if !fr.cur.TryNext(fr.col.ctx) {
+		defer fr.Close()
+		if !fr.cur.TryNext(fr.getContext()) {
 			return mongo.ErrNoDocuments
 		}
 		return fr.cur.Decode(val)
@@ -54,21 +66,116 @@ func (fr *FindResult) All(val interface{}) (err error) {
 	if fr.err != nil {
 		return fr.err
 	}
-	var ctx context.Context
-	if fr.col == nil {
-		ctx = context.Background()
-	} else {
-		ctx = fr.col.ctx
-	}
+	ctx := fr.getContext()
 	if fr.cur == nil {
 		return errors.ErrNoCursor
 	}
 	if !fr.cur.TryNext(ctx) {
+		_ = fr.Close()
 		return ctx.Err()
 	}
 	return fr.cur.All(ctx, val)
 }
 
+// Next advances the cursor to the next document, fetching the next batch
+// when needed. It returns false and closes the cursor once the cursor is
+// exhausted or fails, in which case GetError returns the error.
+func (fr *FindResult) Next() (ok bool) {
+	if fr.err != nil {
+		return false
+	}
+	if fr.cur == nil {
+		fr.err = errors.ErrNoCursor
+		return false
+	}
+	if fr.cur.Next(fr.getContext()) {
+		return true
+	}
+	_ = fr.Close()
+	return false
+}
+
+// Decode decodes the current document of the cursor into val.
+func (fr *FindResult) Decode(val interface{}) (err error) {
+	if fr.err != nil {
+		return fr.err
+	}
+	if fr.cur == nil {
+		return errors.ErrNoCursor
+	}
+	return fr.cur.Decode(val)
+}
+
+// Close closes the cursor. It is safe to call Close more than once.
+func (fr *FindResult) Close() (err error) {
+	if fr.cur == nil {
+		return nil
+	}
+	// close with a fresh context, as the context of the query may be done
+	return fr.cur.Close(context.Background())
+}
+
+// Stream sends the documents of the cursor on docs, waiting for each one to
+// be received before reading the next, so that memory use is bounded by the
+// batch size. Both channels are closed when the cursor is exhausted, fails
+// or ctx is done, and errs then yields the error if any. The cursor is
+// always closed. Cancel ctx to stop receiving before the end.
+func (fr *FindResult) Stream(ctx context.Context) (docs <-chan bson.Raw, errs <-chan error) {
+	_docs := make(chan bson.Raw)
+	_errs := make(chan error, 1)
+	go func() {
+		defer close(_errs)
+		defer close(_docs)
+		if err := fr.each(ctx, func(raw bson.Raw) (err error) {
+			select {
+			case _docs <- raw:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}); err != nil {
+			_errs <- err
+		}
+	}()
+	return _docs, _errs
+}
+
+// each calls fn with a copy of each document of the cursor and closes the
+// cursor when done.
+func (fr *FindResult) each(ctx context.Context, fn func(raw bson.Raw) (err error)) (err error) {
+	if fr.err != nil {
+		return fr.err
+	}
+	if fr.cur == nil {
+		return errors.ErrNoCursor
+	}
+	defer fr.Close()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fr.cur.Next(ctx) {
+			break
+		}
+		raw := make(bson.Raw, len(fr.cur.Current))
+		copy(raw, fr.cur.Current)
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+	if err := fr.cur.Err(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+func (fr *FindResult) getContext() (ctx context.Context) {
+	if fr.col == nil {
+		return context.Background()
+	}
+	return fr.col.ctx
+}
+
 func (fr *FindResult) GetCol() (col *Col) {
 	return fr.col
 }
@@ -0,0 +1,75 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func setupResultTest(n int) (to *ColTestObject, err error) {
+	to, err = setupColTest()
+	if err != nil {
+		return nil, err
+	}
+	var docs []interface{}
+	for i := 0; i < n; i++ {
+		docs = append(docs, TestDocument{Key: fmt.Sprintf("value-%d", i), Value: i})
+	}
+	if _, err := to.col.InsertMany(docs); err != nil {
+		return nil, err
+	}
+	return to, nil
+}
+
+func TestFindResult_Next_Decode(t *testing.T) {
+	n := 10
+	to, err := setupResultTest(n)
+	require.Nil(t, err)
+
+	fr := to.col.Find(nil, &FindOptions{Sort: bson.D{{Key: "value", Value: 1}}, BatchSize: 3})
+	i := 0
+	for fr.Next() {
+		var doc TestDocument
+		err = fr.Decode(&doc)
+		require.Nil(t, err)
+		require.Equal(t, i, doc.Value)
+		i++
+	}
+	require.Nil(t, fr.GetError())
+	require.Equal(t, n, i)
+	require.Nil(t, fr.Close())
+
+	cleanupColTest(to)
+}
+
+func TestFindResult_Stream(t *testing.T) {
+	n := 10
+	to, err := setupResultTest(n)
+	require.Nil(t, err)
+
+	docs, errs := to.col.Find(nil, &FindOptions{BatchSize: 2}).Stream(context.Background())
+	i := 0
+	for raw := range docs {
+		var doc TestDocument
+		err = bson.Unmarshal(raw, &doc)
+		require.Nil(t, err)
+		i++
+	}
+	require.Nil(t, <-errs)
+	require.Equal(t, n, i)
+
+	// typed stream stopped early
+	ctx, cancel := context.WithCancel(context.Background())
+	typedDocs, errs := NewTypedCol[TestDocument](to.col).Stream(ctx, nil, &FindOptions{BatchSize: 2})
+	doc := <-typedDocs
+	require.NotEmpty(t, doc.Key)
+	cancel()
+	for range typedDocs {
+	}
+	require.True(t, errors.Is(<-errs, context.Canceled))
+
+	cleanupColTest(to)
+}
@@ -2,44 +2,104 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/crawlab-team/go-trace"
 	"go.mongodb.org/mongo-driver/mongo"
+	"time"
 )
 
-func RunTransaction(fn func(mongo.SessionContext) error) (err error) {
-	return RunTransactionWithContext(context.Background(), fn)
+const (
+	errorLabelTransientTransactionError      = "TransientTransactionError"
+	errorLabelUnknownTransactionCommitResult = "UnknownTransactionCommitResult"
+)
+
+func RunTransaction(fn func(mongo.SessionContext) error, opts ...TransactionOption) (err error) {
+	return RunTransactionWithContext(context.Background(), fn, opts...)
 }
 
-func RunTransactionWithContext(ctx context.Context, fn func(mongo.SessionContext) error) (err error) {
-	// default client
-	c, err := GetMongoClient()
-	if err != nil {
-		return err
+// RunTransactionWithContext runs fn in a transaction and commits it. The
+// transaction is aborted if fn fails, and the whole transaction is retried
+// with backoff on transient transaction errors, as is the commit on unknown
+// commit results. The session is always ended.
+func RunTransactionWithContext(ctx context.Context, fn func(mongo.SessionContext) error, opts ...TransactionOption) (err error) {
+	// options
+	_opts := &TransactionOptions{
+		maxRetryTime: DefaultTransactionMaxRetryTime,
+	}
+	for _, op := range opts {
+		op(_opts)
 	}
 
-	// start session
-	s, err := c.StartSession()
-	if err != nil {
-		return trace.TraceError(err)
+	// client
+	c := _opts.client
+	if c == nil {
+		c, err = GetMongoClient()
+		if err != nil {
+			return err
+		}
 	}
 
-	// start transaction
-	if err := s.StartTransaction(); err != nil {
+	// start session
+	s, err := c.StartSession(_opts.sessionOptions)
+	if err != nil {
 		return trace.TraceError(err)
 	}
+	defer s.EndSession(context.Background())
 
 	// perform operation
-	if err := mongo.WithSession(ctx, s, func(sc mongo.SessionContext) error {
-		if err := fn(sc); err != nil {
-			return trace.TraceError(err)
-		}
-		if err = s.CommitTransaction(sc); err != nil {
-			return trace.TraceError(err)
-		}
-		return nil
-	}); err != nil {
-		return trace.TraceError(err)
+	txnOpts := _opts.toTransactionOptions()
+	return mongo.WithSession(ctx, s, func(sc mongo.SessionContext) error {
+		return backoff.Retry(func() error {
+			// start transaction
+			if err := s.StartTransaction(txnOpts); err != nil {
+				return backoff.Permanent(trace.TraceError(err))
+			}
+
+			// run callback
+			if err := fn(sc); err != nil {
+				_ = s.AbortTransaction(context.Background())
+				if hasErrorLabel(err, errorLabelTransientTransactionError) {
+					return err
+				}
+				return backoff.Permanent(trace.TraceError(err))
+			}
+
+			// commit transaction, retrying on unknown commit results
+			err := backoff.Retry(func() error {
+				err := s.CommitTransaction(sc)
+				if err != nil && !hasErrorLabel(err, errorLabelUnknownTransactionCommitResult) {
+					return backoff.Permanent(err)
+				}
+				return err
+			}, newTransactionBackOff(ctx, _opts.maxRetryTime))
+			if err == nil {
+				return nil
+			}
+			if hasErrorLabel(err, errorLabelTransientTransactionError) {
+				// retry the whole transaction
+				return err
+			}
+			return backoff.Permanent(trace.TraceError(err))
+		}, newTransactionBackOff(ctx, _opts.maxRetryTime))
+	})
+}
+
+func newTransactionBackOff(ctx context.Context, maxRetryTime time.Duration) (b backoff.BackOff) {
+	if maxRetryTime <= 0 {
+		return &backoff.StopBackOff{}
 	}
+	bp := backoff.NewExponentialBackOff()
+	bp.InitialInterval = 50 * time.Millisecond
+	bp.MaxInterval = 5 * time.Second
+	bp.MaxElapsedTime = maxRetryTime
+	return backoff.WithContext(bp, ctx)
+}
 
-	return nil
+func hasErrorLabel(err error, label string) bool {
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		return se.HasErrorLabel(label)
+	}
+	return false
 }
@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"time"
+)
+
+const DefaultTransactionMaxRetryTime = 2 * time.Minute
+
+type TransactionOption func(options *TransactionOptions)
+
+type TransactionOptions struct {
+	client         *mongo.Client
+	sessionOptions *options.SessionOptions
+	readConcern    *readconcern.ReadConcern
+	writeConcern   *writeconcern.WriteConcern
+	readPreference *readpref.ReadPref
+	maxRetryTime   time.Duration
+}
+
+func (opts *TransactionOptions) toTransactionOptions() (_opts *options.TransactionOptions) {
+	_opts = options.Transaction()
+	if opts.readConcern != nil {
+		_opts.SetReadConcern(opts.readConcern)
+	}
+	if opts.writeConcern != nil {
+		_opts.SetWriteConcern(opts.writeConcern)
+	}
+	if opts.readPreference != nil {
+		_opts.SetReadPreference(opts.readPreference)
+	}
+	return _opts
+}
+
+// WithTransactionClient runs the transaction on c instead of the default client.
+func WithTransactionClient(c *mongo.Client) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.client = c
+	}
+}
+
+func WithTransactionSessionOptions(opts *options.SessionOptions) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.sessionOptions = opts
+	}
+}
+
+func WithTransactionReadConcern(rc *readconcern.ReadConcern) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.readConcern = rc
+	}
+}
+
+func WithTransactionWriteConcern(wc *writeconcern.WriteConcern) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.writeConcern = wc
+	}
+}
+
+func WithTransactionReadPreference(rp *readpref.ReadPref) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.readPreference = rp
+	}
+}
+
+// WithTransactionMaxRetryTime bounds the time spent retrying transient
+// transaction and commit errors. Zero disables retries.
+func WithTransactionMaxRetryTime(d time.Duration) TransactionOption {
+	return func(options *TransactionOptions) {
+		options.maxRetryTime = d
+	}
+}
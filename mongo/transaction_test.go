@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	goerrors "errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"testing"
+)
+
+func TestRunTransaction(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	if !isReplicaSet(to.col) {
+		t.Skip("transactions require a replica set")
+	}
+	defer cleanupColTest(to)
+
+	// create the collection outside of the transaction
+	_, err = to.col.Insert(bson.M{"key": "init"})
+	require.Nil(t, err)
+
+	err = RunTransaction(func(sc mongo.SessionContext) error {
+		_, err := to.col.WithContext(sc).Insert(bson.M{"key": "committed"})
+		return err
+	},
+		WithTransactionClient(to.col.db.Client()),
+		WithTransactionReadConcern(readconcern.Snapshot()),
+		WithTransactionWriteConcern(writeconcern.New(writeconcern.WMajority())),
+	)
+	require.Nil(t, err)
+
+	total, err := to.col.Count(bson.M{"key": "committed"})
+	require.Nil(t, err)
+	require.Equal(t, 1, total)
+}
+
+func TestRunTransaction_Abort(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	if !isReplicaSet(to.col) {
+		t.Skip("transactions require a replica set")
+	}
+	defer cleanupColTest(to)
+
+	_, err = to.col.Insert(bson.M{"key": "init"})
+	require.Nil(t, err)
+
+	fnErr := goerrors.New("callback failed")
+	err = RunTransaction(func(sc mongo.SessionContext) error {
+		if _, err := to.col.WithContext(sc).Insert(bson.M{"key": "aborted"}); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	require.True(t, goerrors.Is(err, fnErr))
+
+	total, err := to.col.Count(bson.M{"key": "aborted"})
+	require.Nil(t, err)
+	require.Equal(t, 0, total)
+}
@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"context"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TypedCol wraps Col for documents of model type T. Find, FindId, FindPage,
+// Insert, InsertMany and Aggregate take and return T instead of interface{};
+// the other Col methods are available as is.
+type TypedCol[T any] struct {
+	*Col
+}
+
+func (col *TypedCol[T]) WithContext(ctx context.Context) (tc *TypedCol[T]) {
+	return NewTypedCol[T](col.Col.WithContext(ctx))
+}
+
+func (col *TypedCol[T]) Insert(doc T) (id primitive.ObjectID, err error) {
+	return col.Col.Insert(doc)
+}
+
+func (col *TypedCol[T]) InsertMany(docs []T) (ids []primitive.ObjectID, err error) {
+	_docs := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		_docs[i] = doc
+	}
+	return col.Col.InsertMany(_docs)
+}
+
+func (col *TypedCol[T]) Find(query bson.M, opts *FindOptions) (docs []T, err error) {
+	if err := col.Col.Find(query, opts).All(&docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (col *TypedCol[T]) FindPage(query bson.M, opts *FindPageOptions) (docs []T, total int, err error) {
+	total, err = col.Col.FindPage(query, opts, &docs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return docs, total, nil
+}
+
+func (col *TypedCol[T]) FindId(id primitive.ObjectID) (doc T, err error) {
+	if err := col.Col.FindId(id).One(&doc); err != nil {
+		return doc, err
+	}
+	return doc, nil
+}
+
+// Stream finds the documents matching query and sends them decoded on docs.
+// See FindResult.Stream for how the channels are closed.
+func (col *TypedCol[T]) Stream(ctx context.Context, query bson.M, opts *FindOptions) (docs <-chan T, errs <-chan error) {
+	return StreamAs[T](ctx, col.Col.Find(query, opts))
+}
+
+func (col *TypedCol[T]) Aggregate(pipeline mongo.Pipeline, opts *options.AggregateOptions) (docs []T, err error) {
+	return AggregateAs[T](col.Col, pipeline, opts)
+}
+
+// AggregateAs runs the pipeline on the collection and decodes the results
+// into R, for aggregations whose output differs from the stored documents.
+func AggregateAs[R any](col *Col, pipeline mongo.Pipeline, opts *options.AggregateOptions) (results []R, err error) {
+	if err := col.Aggregate(pipeline, opts).All(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// StreamAs is like FindResult.Stream but decodes the documents into T.
+func StreamAs[T any](ctx context.Context, fr *FindResult) (docs <-chan T, errs <-chan error) {
+	_docs := make(chan T)
+	_errs := make(chan error, 1)
+	go func() {
+		defer close(_errs)
+		defer close(_docs)
+		if err := fr.each(ctx, func(raw bson.Raw) (err error) {
+			var doc T
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return trace.TraceError(err)
+			}
+			select {
+			case _docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}); err != nil {
+			_errs <- err
+		}
+	}()
+	return _docs, _errs
+}
+
+func NewTypedCol[T any](col *Col) (tc *TypedCol[T]) {
+	return &TypedCol[T]{
+		Col: col,
+	}
+}
+
+func GetMongoTypedCol[T any](colName string, opts ...ColOption) (tc *TypedCol[T]) {
+	return NewTypedCol[T](GetMongoCol(colName, opts...))
+}
+
+func GetMongoTypedColWithDb[T any](colName string, db *mongo.Database, opts ...ColOption) (tc *TypedCol[T]) {
+	return NewTypedCol[T](GetMongoColWithDb(colName, db, opts...))
+}
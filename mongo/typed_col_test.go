@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestTypedCol_Insert_FindId(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	col := NewTypedCol[TestDocument](to.col)
+	id, err := col.Insert(TestDocument{Key: "value", Tags: []string{"a"}})
+	require.Nil(t, err)
+
+	doc, err := col.FindId(id)
+	require.Nil(t, err)
+	require.Equal(t, "value", doc.Key)
+	require.Equal(t, []string{"a"}, doc.Tags)
+
+	cleanupColTest(to)
+}
+
+func TestTypedCol_InsertMany_Find(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	col := NewTypedCol[TestDocument](to.col)
+	n := 10
+	var docs []TestDocument
+	for i := 0; i < n; i++ {
+		docs = append(docs, TestDocument{Key: fmt.Sprintf("value-%d", i), Value: i})
+	}
+	ids, err := col.InsertMany(docs)
+	require.Nil(t, err)
+	require.Len(t, ids, n)
+
+	resDocs, err := col.Find(bson.M{"value": bson.M{"$gte": 5}}, &FindOptions{Sort: bson.D{{Key: "value", Value: 1}}})
+	require.Nil(t, err)
+	require.Len(t, resDocs, n-5)
+	require.Equal(t, "value-5", resDocs[0].Key)
+
+	cleanupColTest(to)
+}
+
+func TestTypedCol_Aggregate(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	col := NewTypedCol[TestDocument](to.col)
+	n := 10
+	for i := 0; i < n; i++ {
+		_, err = col.Insert(TestDocument{Key: fmt.Sprintf("%d", i%2), Value: 1})
+		require.Nil(t, err)
+	}
+
+	results, err := AggregateAs[TestAggregateResult](col.Col, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$key"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}, nil)
+	require.Nil(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, n/2, results[0].Count)
+
+	cleanupColTest(to)
+}
@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// VersionField holds the version of the documents of versioned collections.
+const VersionField = "version"
+
+// UpdateIdWithVersion updates the document only if it is at version, and
+// increments the version. It returns errors.ErrorMongoVersionConflict if the
+// document is at another version, and mongo.ErrNoDocuments if it does not
+// exist.
+func (col *Col) UpdateIdWithVersion(id primitive.ObjectID, version int64, update interface{}) (err error) {
+	_col := *col
+	_col.versioned = true
+	update, err = _col.getUpdate(update, false)
+	if err != nil {
+		return err
+	}
+	res, err := col.c.UpdateOne(col.ctx, col.getQuery(bson.M{"_id": id, VersionField: version}), update)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if res.MatchedCount == 0 {
+		return col.getVersionConflictError(id)
+	}
+	return nil
+}
+
+// ReplaceIdWithVersion replaces the document only if it is at version, and
+// sets the version of doc to the next one. It returns the same errors as
+// UpdateIdWithVersion.
+func (col *Col) ReplaceIdWithVersion(id primitive.ObjectID, version int64, doc interface{}) (err error) {
+	d, err := toBsonD(doc)
+	if err != nil {
+		return err
+	}
+	d = setBsonFields(d, bson.D{{Key: VersionField, Value: version}})
+	_col := *col
+	_col.versioned = true
+	query, _doc, err := _col.getReplace(col.getQuery(bson.M{"_id": id}), d)
+	if err != nil {
+		return err
+	}
+	res, err := col.c.ReplaceOne(col.ctx, query, _doc)
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if res.MatchedCount == 0 {
+		return col.getVersionConflictError(id)
+	}
+	return nil
+}
+
+// getVersionConflictError tells a missing document from a version conflict
+// after an update by version matched nothing.
+func (col *Col) getVersionConflictError(id primitive.ObjectID) (err error) {
+	total, err := col.c.CountDocuments(col.ctx, col.getQuery(bson.M{"_id": id}))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	if total == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return trace.TraceError(errors.ErrorMongoVersionConflict)
+}
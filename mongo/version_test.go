@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"context"
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type TestVersionDocument struct {
+	Id      primitive.ObjectID `bson:"_id,omitempty"`
+	Key     string             `bson:"key"`
+	Version int64              `bson:"version"`
+}
+
+func TestCol_getReplace_Version(t *testing.T) {
+	col := &Col{ctx: context.Background(), versioned: true}
+
+	query, doc, err := col.getReplace(bson.M{"key": "a"}, TestVersionDocument{Key: "b", Version: 3})
+	require.Nil(t, err)
+	require.Equal(t, bson.M{"key": "a", VersionField: int64(3)}, query)
+	version, ok := getBsonInt(doc.(bson.D), VersionField)
+	require.True(t, ok)
+	require.Equal(t, int64(4), version)
+
+	_, _, err = col.getReplace(bson.M{"key": "a"}, bson.M{"key": "b"})
+	require.True(t, goerrors.Is(err, errors.ErrMissingValue))
+
+	update, err := col.getUpdate(bson.M{"$set": bson.M{"key": "b"}}, false)
+	require.Nil(t, err)
+	require.Equal(t, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "key", Value: "b"}}},
+		{Key: "$inc", Value: bson.D{{Key: VersionField, Value: int64(1)}}},
+	}, update)
+
+	doc, err = col.getInsertDoc(bson.M{"key": "a"})
+	require.Nil(t, err)
+	require.Equal(t, bson.D{{Key: "key", Value: "a"}, {Key: VersionField, Value: int64(1)}}, doc)
+}
+
+func TestCol_Version(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColVersion())
+
+	id, err := col.Insert(TestVersionDocument{Key: "a"})
+	require.Nil(t, err)
+	var doc TestVersionDocument
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), doc.Version)
+
+	// update at the expected version
+	err = col.UpdateIdWithVersion(id, 1, bson.M{"$set": bson.M{"key": "b"}})
+	require.Nil(t, err)
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "b", doc.Key)
+	require.Equal(t, int64(2), doc.Version)
+
+	// stale version
+	err = col.UpdateIdWithVersion(id, 1, bson.M{"$set": bson.M{"key": "c"}})
+	require.True(t, goerrors.Is(err, errors.ErrorMongoVersionConflict))
+	err = col.UpdateIdWithVersion(primitive.NewObjectID(), 1, bson.M{"$set": bson.M{"key": "c"}})
+	require.Equal(t, mongo.ErrNoDocuments, err)
+
+	// replace with the version of the document
+	doc.Key = "c"
+	err = col.ReplaceId(id, doc)
+	require.Nil(t, err)
+	err = col.ReplaceId(id, doc)
+	require.True(t, goerrors.Is(err, errors.ErrorMongoVersionConflict))
+	err = col.ReplaceIdWithVersion(id, 3, TestVersionDocument{Key: "d"})
+	require.Nil(t, err)
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, "d", doc.Key)
+	require.Equal(t, int64(4), doc.Version)
+
+	// unchecked updates increment the version
+	err = col.UpdateId(id, bson.M{"$set": bson.M{"key": "e"}})
+	require.Nil(t, err)
+	err = col.FindId(id).One(&doc)
+	require.Nil(t, err)
+	require.Equal(t, int64(5), doc.Version)
+
+	// inserted documents start at version 1
+	ids, err := col.InsertMany([]interface{}{TestVersionDocument{Key: "f"}, TestVersionDocument{Key: "g"}})
+	require.Nil(t, err)
+	for _, id := range ids {
+		err = col.FindId(id).One(&doc)
+		require.Nil(t, err)
+		require.Equal(t, int64(1), doc.Version)
+	}
+	err = col.UpdateIdWithVersion(ids[0], 1, bson.M{"$set": bson.M{"key": "h"}})
+	require.Nil(t, err)
+
+	cleanupColTest(to)
+}
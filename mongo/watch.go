@@ -0,0 +1,208 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	ChangeEventOperationTypeInsert     = "insert"
+	ChangeEventOperationTypeUpdate     = "update"
+	ChangeEventOperationTypeReplace    = "replace"
+	ChangeEventOperationTypeDelete     = "delete"
+	ChangeEventOperationTypeDrop       = "drop"
+	ChangeEventOperationTypeRename     = "rename"
+	ChangeEventOperationTypeInvalidate = "invalidate"
+)
+
+const DefaultResumeTokenColName = "_resume_tokens"
+
+// ChangeEvent is a change stream event of a collection.
+type ChangeEvent struct {
+	Id                bson.Raw                      `bson:"_id"`
+	OperationType     string                        `bson:"operationType"`
+	FullDocument      bson.Raw                      `bson:"fullDocument,omitempty"`
+	DocumentKey       bson.M                        `bson:"documentKey,omitempty"`
+	UpdateDescription *ChangeEventUpdateDescription `bson:"updateDescription,omitempty"`
+	Ns                ChangeEventNamespace          `bson:"ns"`
+	ClusterTime       primitive.Timestamp           `bson:"clusterTime"`
+}
+
+type ChangeEventNamespace struct {
+	Db  string `bson:"db"`
+	Col string `bson:"coll"`
+}
+
+type ChangeEventUpdateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// DecodeFullDocument decodes the full document into val. It returns
+// mongo.ErrNoDocuments if the event carries no full document.
+func (evt *ChangeEvent) DecodeFullDocument(val interface{}) (err error) {
+	if len(evt.FullDocument) == 0 {
+		return mongo.ErrNoDocuments
+	}
+	if err := bson.Unmarshal(evt.FullDocument, val); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// GetDocumentId returns the _id of the changed document.
+func (evt *ChangeEvent) GetDocumentId() (id interface{}) {
+	return evt.DocumentKey["_id"]
+}
+
+// ResumeTokenStore checkpoints the resume tokens of change streams by key.
+type ResumeTokenStore interface {
+	// Load returns the saved token of the key, or nil if there is none.
+	Load(key string) (token bson.Raw, err error)
+	Save(key string, token bson.Raw) (err error)
+}
+
+// MongoResumeTokenStore saves resume tokens in a mongo collection.
+type MongoResumeTokenStore struct {
+	col *Col
+}
+
+type resumeTokenDocument struct {
+	Key      string    `bson:"_id"`
+	Token    bson.Raw  `bson:"token"`
+	UpdateTs time.Time `bson:"update_ts"`
+}
+
+func (s *MongoResumeTokenStore) Load(key string) (token bson.Raw, err error) {
+	var doc resumeTokenDocument
+	if err := s.col.Find(bson.M{"_id": key}, nil).One(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, trace.TraceError(err)
+	}
+	return doc.Token, nil
+}
+
+func (s *MongoResumeTokenStore) Save(key string, token bson.Raw) (err error) {
+	return s.col.UpdateWithOptions(bson.M{"_id": key}, bson.M{
+		"$set": bson.M{
+			"token":     token,
+			"update_ts": time.Now(),
+		},
+	}, options.Update().SetUpsert(true))
+}
+
+func NewMongoResumeTokenStore(col *Col) (s *MongoResumeTokenStore) {
+	return &MongoResumeTokenStore{
+		col: col,
+	}
+}
+
+// GetMongoResumeTokenStore returns a store saving tokens in the
+// DefaultResumeTokenColName collection of the default database.
+func GetMongoResumeTokenStore() (s *MongoResumeTokenStore) {
+	return NewMongoResumeTokenStore(GetMongoCol(DefaultResumeTokenColName))
+}
+
+type WatchOptions struct {
+	// FullDocument looks up the current document of update events.
+	FullDocument bool
+	BatchSize    int
+	MaxAwaitTime time.Duration
+
+	// StartAtOperationTime is used when no resume token is saved.
+	StartAtOperationTime *primitive.Timestamp
+
+	// TokenStore checkpoints the resume token after each handled event, so
+	// that watching again with the same TokenKey continues after it, also
+	// after invalidate events. This requires MongoDB 4.2 or later.
+	TokenStore ResumeTokenStore
+
+	// TokenKey defaults to "<db>.<collection>".
+	TokenKey string
+}
+
+// toChangeStreamOptions starts after token if it is set. Unlike resumeAfter,
+// startAfter accepts the token of an invalidate event, e.g. after the
+// collection was dropped or renamed.
+func (opts *WatchOptions) toChangeStreamOptions(token bson.Raw) (csOpts *options.ChangeStreamOptions) {
+	csOpts = options.ChangeStream()
+	if opts.FullDocument {
+		csOpts.SetFullDocument(options.UpdateLookup)
+	}
+	if opts.BatchSize != 0 {
+		csOpts.SetBatchSize(int32(opts.BatchSize))
+	}
+	if opts.MaxAwaitTime != 0 {
+		csOpts.SetMaxAwaitTime(opts.MaxAwaitTime)
+	}
+	if token != nil {
+		csOpts.SetStartAfter(token)
+	} else if opts.StartAtOperationTime != nil {
+		csOpts.SetStartAtOperationTime(opts.StartAtOperationTime)
+	}
+	return csOpts
+}
+
+// Watch opens a change stream on the collection and calls handler for each
+// event until the context of the collection is done, the stream fails or
+// handler returns an error, which is then returned. Bind a context with
+// WithContext to stop watching.
+func (col *Col) Watch(pipeline mongo.Pipeline, opts *WatchOptions, handler func(evt *ChangeEvent) (err error)) (err error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	tokenKey := opts.TokenKey
+	if tokenKey == "" {
+		tokenKey = fmt.Sprintf("%s.%s", col.db.Name(), col.c.Name())
+	}
+
+	// saved token
+	var token bson.Raw
+	if opts.TokenStore != nil {
+		token, err = opts.TokenStore.Load(tokenKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// open change stream
+	cs, err := col.c.Watch(col.ctx, pipeline, opts.toChangeStreamOptions(token))
+	if err != nil {
+		return trace.TraceError(err)
+	}
+	defer cs.Close(context.Background())
+
+	// handle events
+	for cs.Next(col.ctx) {
+		var evt ChangeEvent
+		if err := cs.Decode(&evt); err != nil {
+			return trace.TraceError(err)
+		}
+		if err := handler(&evt); err != nil {
+			return err
+		}
+		if opts.TokenStore != nil {
+			if err := opts.TokenStore.Save(tokenKey, cs.ResumeToken()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := col.ctx.Err(); err != nil {
+		return err
+	}
+	if err := cs.Err(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func isReplicaSet(col *Col) bool {
+	var res bson.M
+	if err := col.db.RunCommand(col.ctx, bson.M{"isMaster": 1}).Decode(&res); err != nil {
+		return false
+	}
+	_, ok := res["setName"]
+	return ok
+}
+
+func TestMongoResumeTokenStore(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	s := NewMongoResumeTokenStore(to.col)
+	token, err := s.Load("key")
+	require.Nil(t, err)
+	require.Nil(t, token)
+
+	raw, err := bson.Marshal(bson.M{"_data": "token"})
+	require.Nil(t, err)
+	err = s.Save("key", raw)
+	require.Nil(t, err)
+
+	token, err = s.Load("key")
+	require.Nil(t, err)
+	require.Equal(t, bson.Raw(raw), token)
+
+	cleanupColTest(to)
+}
+
+func TestWatchOptions_toChangeStreamOptions(t *testing.T) {
+	ts := &primitive.Timestamp{T: 1}
+	opts := &WatchOptions{StartAtOperationTime: ts}
+	csOpts := opts.toChangeStreamOptions(nil)
+	require.Equal(t, ts, csOpts.StartAtOperationTime)
+	require.Nil(t, csOpts.StartAfter)
+
+	// saved tokens may be the ones of invalidate events, which only
+	// startAfter accepts
+	token, err := bson.Marshal(bson.M{"_data": "token"})
+	require.Nil(t, err)
+	csOpts = opts.toChangeStreamOptions(token)
+	require.Equal(t, bson.Raw(token), csOpts.StartAfter)
+	require.Nil(t, csOpts.ResumeAfter)
+	require.Nil(t, csOpts.StartAtOperationTime)
+}
+
+func TestCol_Watch(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	if !isReplicaSet(to.col) {
+		t.Skip("change streams require a replica set")
+	}
+	defer cleanupColTest(to)
+
+	store := NewMongoResumeTokenStore(GetMongoColWithDb("test_resume_tokens", to.col.db))
+	watch := func(n int) (keys []string, err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = to.col.WithContext(ctx).Watch(nil, &WatchOptions{TokenStore: store}, func(evt *ChangeEvent) (err error) {
+			var doc TestDocument
+			if err := evt.DecodeFullDocument(&doc); err != nil {
+				return err
+			}
+			keys = append(keys, doc.Key)
+			if len(keys) == n {
+				cancel()
+			}
+			return nil
+		})
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		return keys, err
+	}
+
+	// first consumer sees the first insert, then stops
+	go func() {
+		time.Sleep(time.Second)
+		_, _ = to.col.Insert(TestDocument{Key: "value-1"})
+	}()
+	keys, err := watch(1)
+	require.Nil(t, err)
+	require.Equal(t, []string{"value-1"}, keys)
+
+	// restarted consumer continues with the inserts made in between
+	_, err = to.col.Insert(TestDocument{Key: "value-2"})
+	require.Nil(t, err)
+	keys, err = watch(1)
+	require.Nil(t, err)
+	require.Equal(t, []string{"value-2"}, keys)
+}
+
+func TestCol_Watch_Invalidate(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+	if !isReplicaSet(to.col) {
+		t.Skip("change streams require a replica set")
+	}
+	defer cleanupColTest(to)
+
+	store := NewMongoResumeTokenStore(GetMongoColWithDb("test_resume_tokens", to.col.db))
+	watch := func(stop func(evt *ChangeEvent) bool) (types []string, err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = to.col.WithContext(ctx).Watch(nil, &WatchOptions{TokenStore: store}, func(evt *ChangeEvent) (err error) {
+			types = append(types, evt.OperationType)
+			if stop(evt) {
+				cancel()
+			}
+			return nil
+		})
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		return types, err
+	}
+
+	// the stream ends with the invalidate event of the dropped collection,
+	// whose token is saved
+	_, err = to.col.Insert(TestDocument{Key: "value-1"})
+	require.Nil(t, err)
+	go func() {
+		time.Sleep(time.Second)
+		_ = to.col.GetCollection().Drop(context.Background())
+	}()
+	types, err := watch(func(evt *ChangeEvent) bool { return false })
+	require.Nil(t, err)
+	require.Equal(t, ChangeEventOperationTypeInvalidate, types[len(types)-1])
+
+	// the consumer can start again after it
+	go func() {
+		time.Sleep(time.Second)
+		_, _ = to.col.Insert(TestDocument{Key: "value-2"})
+	}()
+	types, err = watch(func(evt *ChangeEvent) bool {
+		return evt.OperationType == ChangeEventOperationTypeInsert
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{ChangeEventOperationTypeInsert}, types)
+}
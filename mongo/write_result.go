@@ -0,0 +1,189 @@
+package mongo
+
+import (
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/go-trace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WriteResult is the result of an update, a replacement or a deletion.
+type WriteResult struct {
+	MatchedCount  int64
+	ModifiedCount int64
+	DeletedCount  int64
+
+	// UpsertedId is the _id of the upserted document, or nil if none was.
+	UpsertedId interface{}
+}
+
+type WriteOptions struct {
+	notFound bool
+}
+
+type WriteOption func(options *WriteOptions)
+
+// WithWriteNotFound makes the write return mongo.ErrNoDocuments, along with
+// the result, if no document matched and none was upserted.
+func WithWriteNotFound() WriteOption {
+	return func(options *WriteOptions) {
+		options.notFound = true
+	}
+}
+
+func (col *Col) UpdateIdWithResult(id primitive.ObjectID, update interface{}, opts ...WriteOption) (res *WriteResult, err error) {
+	update, err = col.getUpdate(update, false)
+	if err != nil {
+		return nil, err
+	}
+	_res, err := col.c.UpdateOne(col.ctx, col.getQuery(bson.M{"_id": id}), update)
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getUpdateWriteResult(_res, opts...)
+}
+
+// UpdateWithResult updates the documents matching query like
+// UpdateWithOptions and returns the counts of matched and modified documents.
+// In soft delete mode, deleted documents are neither matched nor modified.
+func (col *Col) UpdateWithResult(query bson.M, update interface{}, updateOpts *options.UpdateOptions, opts ...WriteOption) (res *WriteResult, err error) {
+	update, err = col.getUpdate(update, updateOpts != nil && updateOpts.Upsert != nil && *updateOpts.Upsert)
+	if err != nil {
+		return nil, err
+	}
+	var _res *mongo.UpdateResult
+	if updateOpts == nil {
+		_res, err = col.c.UpdateMany(col.ctx, col.getQuery(query), update)
+	} else {
+		_res, err = col.c.UpdateMany(col.ctx, col.getQuery(query), update, updateOpts)
+	}
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getUpdateWriteResult(_res, opts...)
+}
+
+func (col *Col) ReplaceIdWithResult(id primitive.ObjectID, doc interface{}, opts ...WriteOption) (res *WriteResult, err error) {
+	return col.ReplaceWithResult(bson.M{"_id": id}, doc, nil, opts...)
+}
+
+// ReplaceWithResult replaces the document matching query like
+// ReplaceWithOptions and returns the counts of matched and modified
+// documents. In versioned collections, errors.ErrorMongoVersionConflict takes
+// precedence over mongo.ErrNoDocuments.
+func (col *Col) ReplaceWithResult(query bson.M, doc interface{}, replaceOpts *options.ReplaceOptions, opts ...WriteOption) (res *WriteResult, err error) {
+	query, doc, err = col.getReplace(col.getQuery(query), doc)
+	if err != nil {
+		return nil, err
+	}
+	var _res *mongo.UpdateResult
+	if replaceOpts == nil {
+		_res, err = col.c.ReplaceOne(col.ctx, query, doc)
+	} else {
+		_res, err = col.c.ReplaceOne(col.ctx, query, doc, replaceOpts)
+	}
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	if col.versioned && _res.MatchedCount == 0 && _res.UpsertedCount == 0 {
+		return getWriteResult(_res), trace.TraceError(errors.ErrorMongoVersionConflict)
+	}
+	return getUpdateWriteResult(_res, opts...)
+}
+
+// DeleteIdWithResult deletes the document like DeleteId. In soft delete
+// mode, the deleted count is the number of documents marked as deleted.
+func (col *Col) DeleteIdWithResult(id primitive.ObjectID, opts ...WriteOption) (res *WriteResult, err error) {
+	if col.softDelete {
+		_res, err := col.c.UpdateOne(col.ctx, col.getQuery(bson.M{"_id": id}), col.getSoftDeleteUpdate())
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		return getSoftDeleteWriteResult(_res, opts...)
+	}
+	_res, err := col.c.DeleteOne(col.ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getDeleteWriteResult(_res, opts...)
+}
+
+// DeleteWithResult deletes the documents matching query like
+// DeleteWithOptions. In soft delete mode, the deleted count is the number of
+// documents marked as deleted.
+func (col *Col) DeleteWithResult(query bson.M, deleteOpts *options.DeleteOptions, opts ...WriteOption) (res *WriteResult, err error) {
+	if col.softDelete {
+		updateOpts := options.Update()
+		if deleteOpts != nil {
+			if deleteOpts.Collation != nil {
+				updateOpts.SetCollation(deleteOpts.Collation)
+			}
+			if deleteOpts.Hint != nil {
+				updateOpts.SetHint(deleteOpts.Hint)
+			}
+		}
+		_res, err := col.c.UpdateMany(col.ctx, col.getQuery(query), col.getSoftDeleteUpdate(), updateOpts)
+		if err != nil {
+			return nil, trace.TraceError(err)
+		}
+		return getSoftDeleteWriteResult(_res, opts...)
+	}
+	var _res *mongo.DeleteResult
+	if deleteOpts == nil {
+		_res, err = col.c.DeleteMany(col.ctx, query)
+	} else {
+		_res, err = col.c.DeleteMany(col.ctx, query, deleteOpts)
+	}
+	if err != nil {
+		return nil, trace.TraceError(err)
+	}
+	return getDeleteWriteResult(_res, opts...)
+}
+
+func getWriteOptions(opts ...WriteOption) (options *WriteOptions) {
+	options = &WriteOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+func getWriteResult(_res *mongo.UpdateResult) (res *WriteResult) {
+	return &WriteResult{
+		MatchedCount:  _res.MatchedCount,
+		ModifiedCount: _res.ModifiedCount,
+		UpsertedId:    _res.UpsertedID,
+	}
+}
+
+func getUpdateWriteResult(_res *mongo.UpdateResult, opts ...WriteOption) (res *WriteResult, err error) {
+	res = getWriteResult(_res)
+	if getWriteOptions(opts...).notFound && res.MatchedCount == 0 && res.UpsertedId == nil {
+		return res, mongo.ErrNoDocuments
+	}
+	return res, nil
+}
+
+func getSoftDeleteWriteResult(_res *mongo.UpdateResult, opts ...WriteOption) (res *WriteResult, err error) {
+	res = &WriteResult{
+		MatchedCount: _res.MatchedCount,
+		DeletedCount: _res.ModifiedCount,
+	}
+	if getWriteOptions(opts...).notFound && res.MatchedCount == 0 {
+		return res, mongo.ErrNoDocuments
+	}
+	return res, nil
+}
+
+func getDeleteWriteResult(_res *mongo.DeleteResult, opts ...WriteOption) (res *WriteResult, err error) {
+	res = &WriteResult{
+		MatchedCount: _res.DeletedCount,
+		DeletedCount: _res.DeletedCount,
+	}
+	if getWriteOptions(opts...).notFound && res.MatchedCount == 0 {
+		return res, mongo.ErrNoDocuments
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+package mongo
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+)
+
+func TestGetUpdateWriteResult(t *testing.T) {
+	res, err := getUpdateWriteResult(&mongo.UpdateResult{MatchedCount: 2, ModifiedCount: 1}, WithWriteNotFound())
+	require.Nil(t, err)
+	require.Equal(t, &WriteResult{MatchedCount: 2, ModifiedCount: 1}, res)
+
+	res, err = getUpdateWriteResult(&mongo.UpdateResult{})
+	require.Nil(t, err)
+	require.Equal(t, int64(0), res.MatchedCount)
+
+	res, err = getUpdateWriteResult(&mongo.UpdateResult{}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	require.NotNil(t, res)
+
+	id := primitive.NewObjectID()
+	res, err = getUpdateWriteResult(&mongo.UpdateResult{UpsertedCount: 1, UpsertedID: id}, WithWriteNotFound())
+	require.Nil(t, err)
+	require.Equal(t, id, res.UpsertedId)
+
+	_, err = getDeleteWriteResult(&mongo.DeleteResult{}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	_, err = getSoftDeleteWriteResult(&mongo.UpdateResult{}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+}
+
+func TestCol_WriteResult(t *testing.T) {
+	to, err := setupColTest()
+	require.Nil(t, err)
+
+	id, err := to.col.Insert(TestDocument{Key: "a", Value: 1})
+	require.Nil(t, err)
+	_, err = to.col.Insert(TestDocument{Key: "a", Value: 2})
+	require.Nil(t, err)
+
+	// update
+	res, err := to.col.UpdateWithResult(bson.M{"key": "a"}, bson.M{"$set": bson.M{"value": 2}}, nil)
+	require.Nil(t, err)
+	require.Equal(t, int64(2), res.MatchedCount)
+	require.Equal(t, int64(1), res.ModifiedCount)
+	res, err = to.col.UpdateIdWithResult(primitive.NewObjectID(), bson.M{"$set": bson.M{"value": 3}}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	require.Equal(t, int64(0), res.MatchedCount)
+
+	// upsert
+	res, err = to.col.UpdateWithResult(bson.M{"key": "b"}, bson.M{"$set": bson.M{"value": 1}}, options.Update().SetUpsert(true), WithWriteNotFound())
+	require.Nil(t, err)
+	require.NotNil(t, res.UpsertedId)
+
+	// replace
+	res, err = to.col.ReplaceIdWithResult(id, TestDocument{Key: "c"}, WithWriteNotFound())
+	require.Nil(t, err)
+	require.Equal(t, int64(1), res.ModifiedCount)
+	_, err = to.col.ReplaceIdWithResult(primitive.NewObjectID(), TestDocument{Key: "c"}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+
+	// delete
+	res, err = to.col.DeleteWithResult(bson.M{"key": "a"}, nil)
+	require.Nil(t, err)
+	require.Equal(t, int64(1), res.DeletedCount)
+	_, err = to.col.DeleteIdWithResult(primitive.NewObjectID(), WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+
+	// soft delete
+	col := GetMongoColWithDb(to.colName, to.col.db, WithColSoftDelete())
+	res, err = col.DeleteIdWithResult(id, WithWriteNotFound())
+	require.Nil(t, err)
+	require.Equal(t, int64(1), res.DeletedCount)
+	_, err = col.DeleteIdWithResult(id, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	_, err = col.UpdateIdWithResult(id, bson.M{"$set": bson.M{"value": 4}}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+	_, err = col.ReplaceIdWithResult(id, TestDocument{Key: "d"}, WithWriteNotFound())
+	require.Equal(t, mongo.ErrNoDocuments, err)
+
+	cleanupColTest(to)
+}
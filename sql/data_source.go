@@ -0,0 +1,48 @@
+package sql
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"github.com/jmoiron/sqlx"
+)
+
+func init() {
+	db.RegisterDataSourceDriver(generic.DataSourceTypeMysql, db.DataSourceDriver{
+		Factory:          newSqlFactory(generic.DataSourceTypeMysql),
+		ConnectionString: getMysqlConnectionString,
+		HealthCheck:      checkSqlConn,
+	})
+	db.RegisterDataSourceDriver(generic.DataSourceTypePostgres, db.DataSourceDriver{
+		Factory:          newSqlFactory(generic.DataSourceTypePostgres),
+		ConnectionString: getPostgresConnectionString,
+		HealthCheck:      checkSqlConn,
+	})
+}
+
+func getMysqlConnectionString(opts *db.DataSourceOptions) (connStr string, err error) {
+	return fmt.Sprintf("%s:%s@(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", opts.Username, opts.Password, opts.Host, opts.Port, opts.Database), nil
+}
+
+func getPostgresConnectionString(opts *db.DataSourceOptions) (connStr string, err error) {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s", opts.Host, opts.Port, opts.Username, opts.Database, opts.Password, "disable"), nil
+}
+
+func newSqlFactory(dataSourceType string) func(opts *db.DataSourceOptions) (conn interface{}, err error) {
+	return func(opts *db.DataSourceOptions) (conn interface{}, err error) {
+		return GetSqlConn(dataSourceType, opts.Host, opts.Port, opts.Username, opts.Password, opts.Database)
+	}
+}
+
+func checkSqlConn(conn interface{}) (err error) {
+	c, ok := conn.(*sqlx.DB)
+	if !ok {
+		return trace.TraceError(errors.ErrInvalidType)
+	}
+	if err := c.Ping(); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package sql
+
+import (
+	"fmt"
+	"github.com/crawlab-team/crawlab-db"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/crawlab-team/go-trace"
+	"github.com/jmoiron/sqlx"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// mysqlMaxLimit is used as LIMIT when only OFFSET is given, as mysql does
+// not support OFFSET without LIMIT.
+const mysqlMaxLimit = "18446744073709551615"
+
+type dialect struct {
+	quote            string
+	regexOp          string
+	placeholder      func(n int) string
+	connectionString func(opts *db.DataSourceOptions) (connStr string, err error)
+}
+
+var dialects = map[string]*dialect{
+	generic.DataSourceTypeMysql: {
+		quote:   "`",
+		regexOp: "REGEXP",
+		placeholder: func(n int) string {
+			return "?"
+		},
+		connectionString: getMysqlConnectionString,
+	},
+	generic.DataSourceTypePostgres: {
+		quote:   `"`,
+		regexOp: "~",
+		placeholder: func(n int) string {
+			return "$" + strconv.Itoa(n)
+		},
+		connectionString: getPostgresConnectionString,
+	},
+}
+
+func getDialect(dataSourceType string) (d *dialect, err error) {
+	d, ok := dialects[dataSourceType]
+	if !ok {
+		err = fmt.Errorf("%w: %s", errors.ErrUnsupportedDataSourceType, dataSourceType)
+		return nil, trace.TraceError(err)
+	}
+	return d, nil
+}
+
+// QuoteIdentifier quotes a table or column name for the given data source
+// type. Dotted names such as "table.column" are quoted part by part.
+func QuoteIdentifier(dataSourceType string, name string) (quoted string, err error) {
+	d, err := getDialect(dataSourceType)
+	if err != nil {
+		return "", err
+	}
+	return d.quoteIdentifier(name), nil
+}
+
+func (d *dialect) quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = d.quote + strings.ReplaceAll(p, d.quote, d.quote+d.quote) + d.quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// GetSqlWhere translates a generic list query into a parameterized condition
+// (without the WHERE keyword) and its arguments. An empty query gives an
+// empty condition.
+func GetSqlWhere(dataSourceType string, query generic.ListQuery) (where string, args []interface{}, err error) {
+	d, err := getDialect(dataSourceType)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := query.Validate(); err != nil {
+		return "", nil, trace.TraceError(err)
+	}
+	b := &queryBuilder{d: d}
+	if len(query) > 0 {
+		where = b.where(query)
+	}
+	return where, b.args, nil
+}
+
+// GetSqlSelect builds a parameterized SELECT statement on the table from a
+// generic list query and list options.
+func GetSqlSelect(dataSourceType string, table string, query generic.ListQuery, opts *generic.ListOptions) (stmt string, args []interface{}, err error) {
+	d, err := getDialect(dataSourceType)
+	if err != nil {
+		return "", nil, err
+	}
+	stmt, args, err = getSqlStatement(d, "SELECT *", table, query)
+	if err != nil {
+		return "", nil, err
+	}
+	if opts == nil {
+		return stmt, args, nil
+	}
+
+	// order by
+	if len(opts.Sort) > 0 {
+		var orders []string
+		for _, s := range opts.Sort {
+			switch s.Direction {
+			case generic.SortDirectionAsc:
+				orders = append(orders, d.quoteIdentifier(s.Key)+" ASC")
+			case generic.SortDirectionDesc:
+				orders = append(orders, d.quoteIdentifier(s.Key)+" DESC")
+			default:
+				return "", nil, trace.TraceError(errors.ErrInvalidSort)
+			}
+		}
+		stmt += " ORDER BY " + strings.Join(orders, ", ")
+	}
+
+	// limit and offset
+	if opts.Skip < 0 || opts.Limit < 0 {
+		return "", nil, trace.TraceError(errors.ErrInvalidOptions)
+	}
+	if opts.Limit > 0 {
+		stmt += " LIMIT " + strconv.Itoa(opts.Limit)
+	} else if opts.Skip > 0 && dataSourceType == generic.DataSourceTypeMysql {
+		stmt += " LIMIT " + mysqlMaxLimit
+	}
+	if opts.Skip > 0 {
+		stmt += " OFFSET " + strconv.Itoa(opts.Skip)
+	}
+
+	return stmt, args, nil
+}
+
+// GetSqlCount builds a parameterized SELECT COUNT(*) statement on the table
+// from a generic list query.
+func GetSqlCount(dataSourceType string, table string, query generic.ListQuery) (stmt string, args []interface{}, err error) {
+	d, err := getDialect(dataSourceType)
+	if err != nil {
+		return "", nil, err
+	}
+	return getSqlStatement(d, "SELECT COUNT(*)", table, query)
+}
+
+func getSqlStatement(d *dialect, prefix string, table string, query generic.ListQuery) (stmt string, args []interface{}, err error) {
+	if err := query.Validate(); err != nil {
+		return "", nil, trace.TraceError(err)
+	}
+	stmt = prefix + " FROM " + d.quoteIdentifier(table)
+	b := &queryBuilder{d: d}
+	if len(query) > 0 {
+		stmt += " WHERE " + b.where(query)
+	}
+	return stmt, b.args, nil
+}
+
+// List selects the rows of the table matching the generic list query into
+// results, which should be a pointer to a slice.
+func List(db *sqlx.DB, table string, query generic.ListQuery, opts *generic.ListOptions, results interface{}) (err error) {
+	stmt, args, err := GetSqlSelect(db.DriverName(), table, query, opts)
+	if err != nil {
+		return err
+	}
+	if err := db.Select(results, stmt, args...); err != nil {
+		return trace.TraceError(err)
+	}
+	return nil
+}
+
+// Count returns the number of rows of the table matching the generic list query.
+func Count(db *sqlx.DB, table string, query generic.ListQuery) (total int, err error) {
+	stmt, args, err := GetSqlCount(db.DriverName(), table, query)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.Get(&total, stmt, args...); err != nil {
+		return 0, trace.TraceError(err)
+	}
+	return total, nil
+}
+
+type queryBuilder struct {
+	d    *dialect
+	args []interface{}
+}
+
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return b.d.placeholder(len(b.args))
+}
+
+func (b *queryBuilder) where(query generic.ListQuery) string {
+	if len(query) == 0 {
+		return "1 = 1"
+	}
+	var conds []string
+	for _, cond := range query {
+		conds = append(conds, b.cond(cond))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func (b *queryBuilder) cond(cond generic.ListQueryCondition) string {
+	// and/or groups
+	if generic.Op(cond.Op).IsGroup() {
+		var groups []string
+		for _, g := range cond.Value.([]generic.ListQuery) {
+			groups = append(groups, "("+b.where(g)+")")
+		}
+		if cond.Op == generic.OpAnd {
+			return "(" + strings.Join(groups, " AND ") + ")"
+		}
+		return "(" + strings.Join(groups, " OR ") + ")"
+	}
+
+	key := b.d.quoteIdentifier(cond.Key)
+	switch cond.Op {
+	case generic.OpEqual:
+		if cond.Value == nil {
+			return key + " IS NULL"
+		}
+		return key + " = " + b.arg(cond.Value)
+	case generic.OpNotEqual:
+		// like mongo $ne, also match null values
+		if cond.Value == nil {
+			return key + " IS NOT NULL"
+		}
+		return fmt.Sprintf("(%s <> %s OR %s IS NULL)", key, b.arg(cond.Value), key)
+	case generic.OpGreaterThan:
+		return key + " > " + b.arg(cond.Value)
+	case generic.OpGreaterThanEqual:
+		return key + " >= " + b.arg(cond.Value)
+	case generic.OpLessThan:
+		return key + " < " + b.arg(cond.Value)
+	case generic.OpLessThanEqual:
+		return key + " <= " + b.arg(cond.Value)
+	case generic.OpIn, generic.OpNotIn:
+		v := reflect.ValueOf(cond.Value)
+		if v.Len() == 0 {
+			if cond.Op == generic.OpIn {
+				return "1 = 0"
+			}
+			return "1 = 1"
+		}
+		var placeholders []string
+		hasNull := false
+		for i := 0; i < v.Len(); i++ {
+			value := v.Index(i).Interface()
+			if value == nil {
+				hasNull = true
+				continue
+			}
+			placeholders = append(placeholders, b.arg(value))
+		}
+		if cond.Op == generic.OpIn {
+			// like mongo $in, a listed null matches null values
+			in := key + " IN (" + strings.Join(placeholders, ", ") + ")"
+			switch {
+			case !hasNull:
+				return in
+			case len(placeholders) == 0:
+				return key + " IS NULL"
+			default:
+				return "(" + in + " OR " + key + " IS NULL)"
+			}
+		}
+		// like mongo $nin, also match null values unless null is listed
+		notIn := key + " NOT IN (" + strings.Join(placeholders, ", ") + ")"
+		switch {
+		case !hasNull:
+			return "(" + notIn + " OR " + key + " IS NULL)"
+		case len(placeholders) == 0:
+			return key + " IS NOT NULL"
+		default:
+			return notIn
+		}
+	case generic.OpExists:
+		if cond.Value.(bool) {
+			return key + " IS NOT NULL"
+		}
+		return key + " IS NULL"
+	case generic.OpContains:
+		return key + " LIKE " + b.arg("%"+escapeLike(cond.Value.(string))+"%")
+	case generic.OpStartsWith:
+		return key + " LIKE " + b.arg(escapeLike(cond.Value.(string))+"%")
+	case generic.OpRegex:
+		return key + " " + b.d.regexOp + " " + b.arg(cond.Value)
+	default:
+		// unreachable after validation
+		return "1 = 0"
+	}
+}
+
+// escapeLike escapes LIKE wildcards with backslash, which is the default
+// escape character of both mysql and postgres.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
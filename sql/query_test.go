@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var testListQuery = generic.ListQuery{
+	{Key: "status", Op: generic.OpEqual, Value: "running"},
+	{Key: "t.count", Op: generic.OpGreaterThanEqual, Value: 1},
+	{Key: "tags", Op: generic.OpIn, Value: []string{"a", "b"}},
+	{Key: "name", Op: generic.OpContains, Value: "50%_off"},
+	{Op: generic.OpOr, Value: []generic.ListQuery{
+		{{Key: "error", Op: generic.OpExists, Value: false}},
+		{{Key: "error", Op: generic.OpNotEqual, Value: ""}, {Key: "error", Op: generic.OpRegex, Value: "^timeout"}},
+	}},
+}
+
+var testListQueryArgs = []interface{}{"running", 1, "a", "b", `%50\%\_off%`, "", "^timeout"}
+
+func TestGetSqlWhere_Mysql(t *testing.T) {
+	where, args, err := GetSqlWhere(generic.DataSourceTypeMysql, testListQuery)
+	require.Nil(t, err)
+	require.Equal(t, "`status` = ? AND `t`.`count` >= ? AND `tags` IN (?, ?) AND `name` LIKE ? AND "+
+		"((`error` IS NULL) OR ((`error` <> ? OR `error` IS NULL) AND `error` REGEXP ?))", where)
+	require.Equal(t, testListQueryArgs, args)
+}
+
+func TestGetSqlWhere_Postgres(t *testing.T) {
+	where, args, err := GetSqlWhere(generic.DataSourceTypePostgres, testListQuery)
+	require.Nil(t, err)
+	require.Equal(t, `"status" = $1 AND "t"."count" >= $2 AND "tags" IN ($3, $4) AND "name" LIKE $5 AND `+
+		`(("error" IS NULL) OR (("error" <> $6 OR "error" IS NULL) AND "error" ~ $7))`, where)
+	require.Equal(t, testListQueryArgs, args)
+}
+
+func TestGetSqlWhere_In(t *testing.T) {
+	cases := []struct {
+		cond  generic.ListQueryCondition
+		where string
+		args  []interface{}
+	}{
+		{generic.ListQueryCondition{Key: "tags", Op: generic.OpNotIn, Value: []string{"a", "b"}}, "(`tags` NOT IN (?, ?) OR `tags` IS NULL)", []interface{}{"a", "b"}},
+		{generic.ListQueryCondition{Key: "tags", Op: generic.OpNotIn, Value: []interface{}{nil, "a"}}, "`tags` NOT IN (?)", []interface{}{"a"}},
+		{generic.ListQueryCondition{Key: "tags", Op: generic.OpNotIn, Value: []interface{}{nil}}, "`tags` IS NOT NULL", nil},
+		{generic.ListQueryCondition{Key: "tags", Op: generic.OpIn, Value: []interface{}{nil, "a"}}, "(`tags` IN (?) OR `tags` IS NULL)", []interface{}{"a"}},
+		{generic.ListQueryCondition{Key: "tags", Op: generic.OpIn, Value: []interface{}{nil}}, "`tags` IS NULL", nil},
+	}
+	for _, c := range cases {
+		where, args, err := GetSqlWhere(generic.DataSourceTypeMysql, generic.ListQuery{c.cond})
+		require.Nil(t, err)
+		require.Equal(t, c.where, where)
+		require.Equal(t, c.args, args)
+	}
+}
+
+func TestGetSqlSelect(t *testing.T) {
+	opts := &generic.ListOptions{
+		Skip: 20,
+		Sort: []generic.ListSort{
+			{Key: "create_ts", Direction: generic.SortDirectionDesc},
+			{Key: "name", Direction: generic.SortDirectionAsc},
+		},
+	}
+	query := generic.ListQuery{{Key: "tags", Op: generic.OpNotIn, Value: []int{}}}
+
+	stmt, args, err := GetSqlSelect(generic.DataSourceTypeMysql, "results", query, opts)
+	require.Nil(t, err)
+	require.Equal(t, "SELECT * FROM `results` WHERE 1 = 1 ORDER BY `create_ts` DESC, `name` ASC LIMIT 18446744073709551615 OFFSET 20", stmt)
+	require.Empty(t, args)
+
+	opts.Limit = 10
+	stmt, _, err = GetSqlSelect(generic.DataSourceTypePostgres, `we"ird`, nil, opts)
+	require.Nil(t, err)
+	require.Equal(t, `SELECT * FROM "we""ird" ORDER BY "create_ts" DESC, "name" ASC LIMIT 10 OFFSET 20`, stmt)
+}
+
+func TestGetSqlCount(t *testing.T) {
+	stmt, args, err := GetSqlCount(generic.DataSourceTypePostgres, "results", generic.ListQuery{
+		{Key: "status", Op: generic.OpEqual, Value: nil},
+		{Key: "count", Op: generic.OpLessThan, Value: 5},
+	})
+	require.Nil(t, err)
+	require.Equal(t, `SELECT COUNT(*) FROM "results" WHERE "status" IS NULL AND "count" < $1`, stmt)
+	require.Equal(t, []interface{}{5}, args)
+}
+
+func TestGetSqlSelect_Error(t *testing.T) {
+	_, _, err := GetSqlSelect("sqlite", "results", nil, nil)
+	require.NotNil(t, err)
+
+	_, _, err = GetSqlSelect(generic.DataSourceTypeMysql, "results", generic.ListQuery{{Key: "a", Op: "like", Value: "x"}}, nil)
+	require.NotNil(t, err)
+
+	_, _, err = GetSqlSelect(generic.DataSourceTypeMysql, "results", nil, &generic.ListOptions{Sort: []generic.ListSort{{Key: "a", Direction: "up"}}})
+	require.NotNil(t, err)
+}
@@ -1,19 +1,26 @@
 package sql
 
 import (
-	"errors"
-	"fmt"
+	"github.com/crawlab-team/crawlab-db"
 	"github.com/crawlab-team/go-trace"
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 )
 
 func GetSqlDatabaseConnectionString(dataSourceType string, host string, port string, username string, password string, database string) (connStr string, err error) {
-	if dataSourceType == "mysql" {
-		connStr = fmt.Sprintf("%s:%s@(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", username, password, host, port, database)
-	} else if dataSourceType == "postgres" {
-		connStr = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s", host, port, username, database, password, "disable")
-	} else {
-		err = errors.New(dataSourceType + " is not implemented")
+	d, err := getDialect(dataSourceType)
+	if err != nil {
+		return "", err
+	}
+	connStr, err = d.connectionString(&db.DataSourceOptions{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		Database: database,
+	})
+	if err != nil {
 		return connStr, trace.TraceError(err)
 	}
 	return connStr, nil
@@ -0,0 +1,34 @@
+package sql
+
+import (
+	goerrors "errors"
+	"github.com/crawlab-team/crawlab-db/errors"
+	"github.com/crawlab-team/crawlab-db/generic"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGetSqlDatabaseConnectionString(t *testing.T) {
+	connStr, err := GetSqlDatabaseConnectionString(generic.DataSourceTypeMysql, "localhost", "3306", "user", "pass", "crawlab")
+	require.Nil(t, err)
+	require.Equal(t, "user:pass@(localhost:3306)/crawlab?charset=utf8&parseTime=True&loc=Local", connStr)
+
+	connStr, err = GetSqlDatabaseConnectionString(generic.DataSourceTypePostgres, "localhost", "5432", "user", "pass", "crawlab")
+	require.Nil(t, err)
+	require.Equal(t, "host=localhost port=5432 user=user dbname=crawlab password=pass sslmode=disable", connStr)
+
+	_, err = GetSqlDatabaseConnectionString("sqlite", "", "", "", "", "")
+	require.True(t, goerrors.Is(err, errors.ErrUnsupportedDataSourceType))
+	_, err = GetSqlDatabaseConnectionString(generic.DataSourceTypeElasticSearch, "localhost", "9200", "", "", "")
+	require.True(t, goerrors.Is(err, errors.ErrUnsupportedDataSourceType))
+}
+
+func TestGetSqlConn(t *testing.T) {
+	// the drivers are registered, opening does not connect
+	for _, dataSourceType := range []string{generic.DataSourceTypeMysql, generic.DataSourceTypePostgres} {
+		conn, err := GetSqlConn(dataSourceType, "localhost", "1", "user", "pass", "crawlab")
+		require.Nil(t, err)
+		require.NotNil(t, conn)
+		require.Nil(t, conn.Close())
+	}
+}